@@ -0,0 +1,178 @@
+package yenc
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FileReport describes a file put together by Assemble
+type FileReport struct {
+	Name string
+	// size of the file from the headers, and bytes written to dst
+	Size, Written int64
+	// number of parts from the headers, zero if not given
+	Total int
+	// every decoded part in the order read, with its body released
+	// once written
+	Parts []*Part
+	// part numbers not found, and those that failed their crc check
+	// with no good copy found
+	Missing, Damaged []int
+	// why readers failed to decode, by position in the arguments
+	Failed map[int]error
+	// whether the whole file crc was given and could be checked, which
+	// needs every part, and whether it matched
+	FileCRCChecked, FileCRCValid bool
+}
+
+// Complete reports whether every part was found, decoded and passed its
+// checks
+func (r *FileReport) Complete() bool {
+	return len(r.Missing) == 0 && len(r.Damaged) == 0 && len(r.Failed) == 0 &&
+		(!r.FileCRCChecked || r.FileCRCValid)
+}
+
+// Assembler accepts the parts of one file in any order and writes each
+// at its offset in an io.WriterAt straight away, unlike SinkAssembler
+// which has to hold early parts back. Each part's data is checked
+// against its pcrc32 as it is written.
+type Assembler struct {
+	w io.WriterAt
+	// total file size
+	size int64
+	// check of each part written, by part number
+	parts   map[int]RangeStatus
+	written int64
+}
+
+// NewAssembler returns an assembler writing a file of size bytes to w.
+// If size is zero the size from the first part's header is used.
+func NewAssembler(w io.WriterAt, size int64) *Assembler {
+	return &Assembler{
+		w:     w,
+		size:  size,
+		parts: make(map[int]RangeStatus),
+	}
+}
+
+// Add writes p's data at its offset. A part that has already been
+// written is ignored, unless the copy written failed its crc and this
+// one passes.
+func (a *Assembler) Add(p *Part) error {
+	if a.size == 0 {
+		a.size = p.HeaderSize
+	}
+	prev, seen := a.parts[p.Number]
+	r := RangeStatus{Part: p.Number, Offset: p.offset(), Length: int64(len(p.Body)), CRC32: p.bodyCRC32(), HasCRC: p.CRC32 > 0}
+	r.Verified = r.HasCRC && r.CRC32 == p.CRC32
+	if seen && (prev.Verified || !r.Verified) {
+		return nil
+	}
+	if _, err := a.w.WriteAt(p.Body, r.Offset); err != nil {
+		return err
+	}
+	if !seen {
+		a.written += r.Length
+	}
+	a.parts[p.Number] = r
+	return nil
+}
+
+// Has reports whether part number n has been written
+func (a *Assembler) Has(n int) bool {
+	_, ok := a.parts[n]
+	return ok
+}
+
+// Done reports whether as many bytes as the file holds have been written
+func (a *Assembler) Done() bool {
+	return a.size > 0 && a.written >= a.size
+}
+
+// Written returns how many bytes of distinct parts have been written
+func (a *Assembler) Written() int64 {
+	return a.written
+}
+
+// Ranges returns the check of each part written so far, in file order
+func (a *Assembler) Ranges() []RangeStatus {
+	ranges := make([]RangeStatus, 0, len(a.parts))
+	for _, r := range a.parts {
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Offset < ranges[j].Offset
+	})
+	return ranges
+}
+
+// Verified reports whether the whole file has been written and every
+// part of it matched its crc
+func (a *Assembler) Verified() bool {
+	if !a.Done() {
+		return false
+	}
+	for _, r := range a.parts {
+		if !r.Verified {
+			return false
+		}
+	}
+	return true
+}
+
+// Assemble decodes the parts of one file from readers, an article each
+// in any order, and writes each part's data at its offset in dst with
+// an Assembler. The report says what was found and whether it checked
+// out; readers that fail to decode are noted there rather than stopping
+// the others. An error is returned if writing fails or the parts belong
+// to more than one file.
+func Assemble(dst io.WriterAt, readers ...io.Reader) (*FileReport, error) {
+	r := &FileReport{Failed: make(map[int]error)}
+	a := NewAssembler(dst, 0)
+	var expectedCRC uint32
+	for i, reader := range readers {
+		res, err := DecodeResult(reader, WithCRCWarnings())
+		if err != nil {
+			r.Failed[i] = err
+			continue
+		}
+		r.Total = max(r.Total, res.Total)
+		if res.FileCRCPresent {
+			expectedCRC = res.FileCRC
+		}
+		for _, p := range res.Parts {
+			if r.Name == "" {
+				r.Name, r.Size = p.Name, p.HeaderSize
+			} else if p.Name != r.Name {
+				return r, fmt.Errorf("yenc: part %d of %s found among the parts of %s", p.Number, p.Name, r.Name)
+			}
+			r.Parts = append(r.Parts, p)
+			if err := a.Add(p); err != nil {
+				return r, err
+			}
+			p.Release()
+		}
+	}
+	r.Written = a.Written()
+	r.Missing = MissingParts(r.Parts, r.Total)
+	// the file crc comes from the part crcs, if they cover the file.
+	// Each is of the bytes written, so they combine to the file's.
+	var fileCRC uint32
+	var next int64
+	for _, rs := range a.Ranges() {
+		if rs.HasCRC && !rs.Verified {
+			r.Damaged = append(r.Damaged, rs.Part)
+		}
+		if rs.Offset == next {
+			fileCRC = combineCRC32(fileCRC, rs.CRC32, rs.Length)
+			next += rs.Length
+		}
+	}
+	sort.Ints(r.Damaged)
+	if expectedCRC > 0 && len(r.Missing) == 0 && next > 0 && next == r.Written {
+		r.FileCRCChecked = true
+		r.FileCRCValid = fileCRC == expectedCRC
+	}
+	return r, nil
+}
@@ -0,0 +1,137 @@
+package yenc
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	data := encodeTestData(3000)
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 1000}, data)
+	f, err := os.Create(filepath.Join(t.TempDir(), "data.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// the file crc is checked even with the parts out of order
+	var readers []io.Reader
+	for i := len(articles) - 1; i >= 0; i-- {
+		readers = append(readers, bytes.NewReader(articles[i].Bytes()))
+	}
+	r, err := Assemble(f, readers...)
+	if err != nil {
+		t.Fatal("expected to assemble: " + err.Error())
+	}
+	if !r.Complete() || !r.FileCRCChecked || !r.FileCRCValid || r.Name != "data.bin" || r.Written != 3000 || r.Total != 3 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	out, _ := os.ReadFile(f.Name())
+	if !bytes.Equal(out, data) {
+		t.Error("assembled file differs from the input")
+	}
+}
+
+func TestAssembleIncomplete(t *testing.T) {
+	data := encodeTestData(3000)
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 1000}, data)
+	a := articles[0].String()
+	i := strings.Index(a, "pcrc32=") + len("pcrc32=")
+	damaged := a[:i] + "ffffffff" + a[i+8:]
+	f, err := os.Create(filepath.Join(t.TempDir(), "data.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := Assemble(f,
+		bytes.NewReader(articles[2].Bytes()),
+		strings.NewReader(damaged),
+		strings.NewReader("not yenc\r\n"))
+	if err != nil {
+		t.Fatal("expected to assemble: " + err.Error())
+	}
+	if r.Complete() || r.FileCRCChecked {
+		t.Fatalf("expected incomplete report got %+v", r)
+	}
+	if len(r.Missing) != 1 || r.Missing[0] != 2 {
+		t.Errorf("expected part 2 missing got %v", r.Missing)
+	}
+	if len(r.Damaged) != 1 || r.Damaged[0] != 1 {
+		t.Errorf("expected part 1 damaged got %v", r.Damaged)
+	}
+	if _, ok := r.Failed[2]; !ok || len(r.Failed) != 1 {
+		t.Errorf("expected the third reader to fail got %v", r.Failed)
+	}
+	out, _ := os.ReadFile(f.Name())
+	if !bytes.Equal(out[2000:], data[2000:]) {
+		t.Error("expected part 3 at its offset")
+	}
+}
+
+// a WriterAt over a fixed buffer
+type bufferAt []byte
+
+func (b bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(b[off:], p), nil
+}
+
+func TestAssemblerOutOfOrder(t *testing.T) {
+	sum := func(s string) uint32 { return crc32.ChecksumIEEE([]byte(s)) }
+	parts := []*Part{
+		{Number: 3, Begin: 7, End: 9, Body: []byte("ghi"), CRC32: sum("ghi")},
+		{Number: 1, Begin: 1, End: 3, Body: []byte("xxx"), CRC32: sum("abc")},
+		{Number: 1, Begin: 1, End: 3, Body: []byte("abc"), CRC32: sum("abc")},
+		{Number: 1, Begin: 1, End: 3, Body: []byte("yyy"), CRC32: sum("abc")},
+		{Number: 2, Begin: 4, End: 6, Body: []byte("def"), CRC32: sum("def")},
+	}
+	buf := make(bufferAt, 9)
+	a := NewAssembler(buf, 9)
+	for i, p := range parts {
+		if err := a.Add(p); err != nil {
+			t.Fatal("expected to add part: " + err.Error())
+		}
+		if i < 4 && a.Done() {
+			t.Fatalf("assembler done after %d parts", i+1)
+		}
+	}
+	if !a.Done() || !a.Verified() || a.Written() != 9 {
+		t.Errorf("expected a verified file of 9 bytes got %d", a.Written())
+	}
+	if string(buf) != "abcdefghi" {
+		t.Errorf("expected abcdefghi got %s", buf)
+	}
+	if r := a.Ranges(); len(r) != 3 || r[0].Part != 1 || r[2].Offset != 6 {
+		t.Errorf("unexpected ranges %+v", r)
+	}
+}
+
+func TestAssemblerTruncatedPart(t *testing.T) {
+	// part 1 sent a byte more than its trailer and range claim
+	articles := []string{
+		"=ybegin part=1 total=2 line=128 size=6 name=x\r\n=ypart begin=1 end=3\r\n\x8b\x8c\x8d\x8e\r\n=yend size=3 part=1\r\n",
+		"=ybegin part=2 total=2 line=128 size=6 name=x\r\n=ypart begin=4 end=6\r\n\x8e\x8f\x90\r\n=yend size=3 part=2\r\n",
+	}
+	buf := make(bufferAt, 6)
+	a := NewAssembler(buf, 6)
+	for _, article := range articles {
+		p, err := Decode(strings.NewReader(article), WithSizePolicy(SizeTruncate))
+		if err != nil {
+			t.Fatal("expected to decode: " + err.Error())
+		}
+		if err := a.Add(p); err != nil {
+			t.Fatal("expected to add part: " + err.Error())
+		}
+	}
+	// the ranges combine to the crc of the file as written
+	var crc uint32
+	for _, r := range a.Ranges() {
+		crc = combineCRC32(crc, r.CRC32, r.Length)
+	}
+	if string(buf) != "abcdef" || crc != crc32.ChecksumIEEE(buf) {
+		t.Errorf("expected the ranges to describe %q got crc %08x", buf, crc)
+	}
+}
@@ -0,0 +1,35 @@
+package yenc
+
+import "io"
+
+// chanReader reads the chunks sent on a channel, see NewChanReader
+type chanReader struct {
+	ch  <-chan []byte
+	buf []byte
+}
+
+// NewChanReader returns a reader over the chunks sent on ch, as
+// produced by many async NNTP clients. Chunks may split lines anywhere;
+// closing ch marks the end of the stream.
+func NewChanReader(ch <-chan []byte) io.Reader {
+	return &chanReader{ch: ch}
+}
+
+func (c *chanReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		chunk, ok := <-c.ch
+		if !ok {
+			return 0, io.EOF
+		}
+		c.buf = chunk
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// DecodeChan decodes every part in the chunks sent on ch until it is
+// closed
+func DecodeChan(ch <-chan []byte, opts ...Option) (*Result, error) {
+	return DecodeResult(NewChanReader(ch), opts...)
+}
@@ -0,0 +1,30 @@
+package yenc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeChan(t *testing.T) {
+	data := encodeTestData(20000)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "chan.bin", int64(len(data)))
+	e.Write(data)
+	e.Close()
+	ch := make(chan []byte)
+	go func() {
+		// awkward chunk sizes, including empty ones, that split lines
+		encoded := buf.Bytes()
+		for i, n := 0, 0; i < len(encoded); i, n = i+n, (n*7+3)%500 {
+			ch <- encoded[i:min(i+n, len(encoded))]
+		}
+		close(ch)
+	}()
+	res, err := DecodeChan(ch)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if len(res.Parts) != 1 || !bytes.Equal(res.Parts[0].Body, data) {
+		t.Errorf("decoded data did not match input")
+	}
+}
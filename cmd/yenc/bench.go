@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc bench [-size n] [-lines n]
+func bench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	size := fs.String("size", "256m", "bytes of synthetic data, k/m/g suffixes allowed")
+	lines := fs.Int("lines", yenc.DefaultLineLength, "encoded chars per line")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc bench [-size 256m] [-lines 128]")
+		fmt.Fprintln(os.Stderr, "measures encode and decode throughput on random data held in memory")
+		fs.PrintDefaults()
+	}
+	if len(parseArgs(fs, args)) > 0 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	n, err := parseSize(*size)
+	if err != nil {
+		return withCode(exitUsage, err)
+	}
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	enc := &yenc.Encoding{LineLength: *lines}
+	encoded := bytes.NewBuffer(make([]byte, 0, enc.TypicalEncodedLen(n)+1024))
+
+	start := time.Now()
+	e := enc.NewEncoder(encoded, "bench.bin", n)
+	if _, err := e.Write(data); err != nil {
+		return err
+	}
+	if err := e.Close(); err != nil {
+		return err
+	}
+	encodeTime := time.Since(start)
+
+	start = time.Now()
+	decoded, err := io.Copy(io.Discard, yenc.NewDecoder(bytes.NewReader(encoded.Bytes())))
+	if err != nil {
+		return err
+	}
+	decodeTime := time.Since(start)
+	if decoded != n {
+		return fmt.Errorf("decoded %d bytes, expected %d", decoded, n)
+	}
+
+	fmt.Printf("%s %s/%s, %d bytes, %d chars per line, %.2f%% overhead\n",
+		runtime.Version(), runtime.GOOS, runtime.GOARCH, n, *lines,
+		100*float64(int64(encoded.Len())-n)/float64(n))
+	kernels := yenc.Features().Kernels
+	fmt.Printf("encode  %8.1f MB/s  %v  (%s)\n", throughput(n, encodeTime), encodeTime.Round(time.Millisecond), kernels["encode"])
+	fmt.Printf("decode  %8.1f MB/s  %v  (%s)\n", throughput(n, decodeTime), decodeTime.Round(time.Millisecond), kernels["decode"])
+	return nil
+}
+
+// decoded megabytes per second
+func throughput(n int64, d time.Duration) float64 {
+	return float64(n) / 1e6 / d.Seconds()
+}
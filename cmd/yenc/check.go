@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// checkRecord is the result of validating one input
+type checkRecord struct {
+	File  string `json:"file"`
+	Valid bool   `json:"valid"`
+	// why the input could not be read
+	Error  string        `json:"error"`
+	Parts  int           `json:"parts"`
+	Issues []issueRecord `json:"issues"`
+}
+
+type issueRecord struct {
+	Line int    `json:"line"`
+	Msg  string `json:"msg"`
+}
+
+// yenc check [-json | -ndjson] file...
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	p := newPrinter(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc check [-json | -ndjson] file...")
+		fmt.Fprintf(os.Stderr, "checks each file against the yenc %s grammar, sizes and crcs\n", yenc.SpecVersion)
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	failed, code := 0, 0
+	for _, file := range files {
+		r, err := validate(file)
+		if !r.Valid {
+			failed++
+			// the first failure decides the exit code
+			if code == 0 {
+				code = exitDecode
+				if err != nil {
+					code = exitIO
+				}
+			}
+		}
+		if err := p.print(r, r.text); err != nil {
+			return err
+		}
+	}
+	if err := p.close(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return withCode(code, fmt.Errorf("%d of %d files failed their checks", failed, len(files)))
+	}
+	return nil
+}
+
+// check file, returning an error only if it couldn't be read
+func validate(file string) (*checkRecord, error) {
+	r := &checkRecord{File: file, Issues: []issueRecord{}}
+	in, err := openInput(file)
+	if err != nil {
+		r.Error = err.Error()
+		return r, err
+	}
+	defer in.Close()
+	report, err := yenc.Validate(in)
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.Parts = report.Parts
+	for _, issue := range report.Issues {
+		r.Issues = append(r.Issues, issueRecord{issue.Line, issue.Msg})
+	}
+	r.Valid = err == nil && report.Valid()
+	return r, err
+}
+
+func (r *checkRecord) text(w io.Writer) {
+	for _, issue := range r.Issues {
+		fmt.Fprintf(w, "%s:%d: %s\n", r.File, issue.Line, issue.Msg)
+	}
+	switch {
+	case r.Error != "":
+		fmt.Fprintf(w, "%s: %s\n", r.File, r.Error)
+	case r.Valid:
+		fmt.Fprintf(w, "%s: ok, %d parts\n", r.File, r.Parts)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc decode [-o out | -d dir | -verify] [-nntp] file...
+func decode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	out := fs.String("o", "-", "file to write the decoded data to")
+	dir := fs.String("d", "", "write each file to this directory under its own name instead")
+	verify := fs.Bool("verify", false, "only check that the input decodes cleanly")
+	nntp := fs.Bool("nntp", false, "inputs are articles as sent by an NNTP server, dots stuffed")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc decode [-o out | -d dir | -verify] [-nntp] file...")
+		fmt.Fprintln(os.Stderr, "decodes yenc data, streaming it to a single output unless -d or -verify is given")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if *dir != "" && *verify {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	var opts []yenc.Option
+	if *nntp {
+		opts = append(opts, yenc.WithNNTP())
+	}
+	switch {
+	case *verify:
+		return verifyInputs(files, opts)
+	case *dir != "":
+		return decodeFiles(files, *dir, opts)
+	}
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, file := range files {
+		if err := decodeStream(w, file, opts); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// decode one input onto the end of w
+func decodeStream(w io.Writer, file string, opts []yenc.Option) error {
+	in, err := openInput(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if _, err := io.Copy(w, yenc.NewDecoder(in, opts...)); err != nil {
+		return decodeError(fmt.Errorf("%s: %w", file, err))
+	}
+	return nil
+}
+
+// write each file found in the inputs to dir
+func decodeFiles(files []string, dir string, opts []yenc.Option) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	e := &yenc.Extractor{Dir: dir, Options: opts}
+	damaged := 0
+	for _, file := range files {
+		in, err := openInput(file)
+		if err != nil {
+			return err
+		}
+		out, err := e.Extract(in)
+		in.Close()
+		if err != nil {
+			return decodeError(fmt.Errorf("%s: %w", file, err))
+		}
+		damaged += report(os.Stdout, out)
+	}
+	if damaged > 0 {
+		return withCode(exitCRC, fmt.Errorf("%d files failed their size or crc checks", damaged))
+	}
+	return nil
+}
+
+// decode each input without keeping the data, listing its parts
+func verifyInputs(files []string, opts []yenc.Option) error {
+	damaged, missing := 0, 0
+	for _, file := range files {
+		in, err := openInput(file)
+		if err != nil {
+			return err
+		}
+		res, err := yenc.DecodeResult(in, append(opts, yenc.WithCRCWarnings())...)
+		in.Close()
+		if err != nil {
+			return decodeError(fmt.Errorf("%s: %w", file, err))
+		}
+		for _, p := range res.Parts {
+			fmt.Println(newPartRecord(p))
+			if len(p.Warnings) > 0 {
+				damaged++
+			}
+		}
+		if res.Multipart {
+			for _, n := range yenc.MissingParts(res.Parts, res.Total) {
+				fmt.Printf("%s: part %d missing\n", file, n)
+				missing++
+			}
+		}
+	}
+	switch {
+	case damaged > 0:
+		return withCode(exitCRC, fmt.Errorf("%d parts failed their size or crc checks", damaged))
+	case missing > 0:
+		return withCode(exitIncomplete, fmt.Errorf("%d parts missing", missing))
+	}
+	return nil
+}
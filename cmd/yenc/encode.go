@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc encode [-o out | -d dir] [-name name] [-line n] [-part-size n] file...
+func encode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	out := fs.String("o", "-", "file to write the encoded data to")
+	dir := fs.String("d", "", "with -part-size, write each part to this directory as name.001.yenc, ...")
+	name := fs.String("name", "", "name to give data read from stdin")
+	line := fs.Int("line", yenc.DefaultLineLength, "encoded chars per line")
+	partSize := fs.String("part-size", "", "split each file into parts of this many bytes, k/m/g suffixes allowed")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc encode [-o out | -d dir] [-name name] [-line n] [-part-size n] file...")
+		fmt.Fprintln(os.Stderr, "encodes files as yenc, one after another in a single output unless -d is given")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	m := &yenc.MultipartEncoder{LineLength: *line}
+	if *partSize != "" {
+		var err error
+		if m.PartSize, err = parseSize(*partSize); err != nil {
+			return withCode(exitUsage, err)
+		}
+	} else if *dir != "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0777); err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := encodeFile(file, *name, m, nil, *dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, file := range files {
+		if err := encodeFile(file, *name, m, w, ""); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encode one file to w, or with a part size set to its own files in
+// dir when that is given
+func encodeFile(file, name string, m *yenc.MultipartEncoder, w io.Writer, dir string) error {
+	src, size, name, err := openSource(file, name)
+	if err != nil {
+		return err
+	}
+	if c, ok := src.(io.Closer); ok {
+		defer c.Close()
+	}
+	switch {
+	case m.PartSize == 0:
+		e := (&yenc.Encoding{LineLength: m.LineLength}).NewEncoder(w, name, size)
+		if _, err := io.Copy(e, io.NewSectionReader(src, 0, size)); err != nil {
+			e.Close()
+			return err
+		}
+		return e.Close()
+	case dir != "":
+		return m.Encode(src, name, size, func(part, total int) (io.Writer, error) {
+			return os.Create(filepath.Join(dir, fmt.Sprintf("%s.%03d.yenc", name, part)))
+		})
+	}
+	return m.Encode(src, name, size, func(part, total int) (io.Writer, error) {
+		return w, nil
+	})
+}
+
+// open a file to encode with its size and the name to give it, name
+// being used for stdin. Stdin is read into memory as the size must be
+// known up front.
+func openSource(file, name string) (io.ReaderAt, int64, string, error) {
+	if file == "" || file == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if name == "" {
+			name = "stdin"
+		}
+		return bytes.NewReader(data), int64(len(data)), name, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, "", err
+	}
+	return f, st.Size(), filepath.Base(file), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// exit codes, so scripts can tell failures apart without reading stderr
+const (
+	// anything not covered below
+	exitFailure = 1
+	// bad command line, as the flag package uses
+	exitUsage = 2
+	// the input isn't usable yenc
+	exitDecode = 3
+	// data decoded but failed its size or crc checks
+	exitCRC = 4
+	// parts of a file are missing
+	exitIncomplete = 5
+	// reading or writing files failed
+	exitIO = 6
+)
+
+// exitError carries the exit code for an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withCode gives err an exit code, nil stays nil
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code, err}
+}
+
+// decodeError marks an error from decoding as exitDecode, unless it came
+// from reading or writing files
+func decodeError(err error) error {
+	if exitCode(err) != exitFailure {
+		return err
+	}
+	return withCode(exitDecode, err)
+}
+
+// exitCode picks the exit code for an error returned by a command
+func exitCode(err error) int {
+	var e *exitError
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var timeout *yenc.TimeoutError
+	var limit *yenc.LimitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &e):
+		return e.code
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.As(err, &timeout):
+		return exitIO
+	case errors.Is(err, yenc.ErrCRCMismatch), errors.Is(err, yenc.ErrSizeMismatch):
+		return exitCRC
+	case errors.Is(err, yenc.ErrTrailerMissing):
+		return exitIncomplete
+	case errors.Is(err, yenc.ErrInconsistentSize), errors.As(err, &limit):
+		return exitDecode
+	}
+	return exitFailure
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/chrisfarms/yenc"
+	"github.com/chrisfarms/yenc/nzb"
+)
+
+// yenc extract [-d dir] [-overwrite] [-name pattern] [-nzb file] file...
+func extract(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	dir := fs.String("d", ".", "directory to write files to")
+	overwrite := fs.Bool("overwrite", false, "replace existing files")
+	name := fs.String("name", "", "only extract files whose name matches this pattern, eg '*.nfo'")
+	nzbFile := fs.String("nzb", "", "check the extracted files against the segment sizes in this NZB")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc extract [-d dir] [-overwrite] [-name pattern] [-nzb file] file...")
+		fmt.Fprintln(os.Stderr, "decodes the files in yenc data or in .eml messages, which may also hold uuencoded files")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if err := os.MkdirAll(*dir, 0777); err != nil {
+		return err
+	}
+	e := &yenc.Extractor{Dir: *dir}
+	if *overwrite {
+		e.Collision = yenc.CollisionOverwrite
+	}
+	if *name != "" {
+		if _, err := path.Match(*name, ""); err != nil {
+			return err
+		}
+		e.Options = append(e.Options, yenc.WithNameGlob(*name))
+	}
+	var index *nzb.NZB
+	if *nzbFile != "" {
+		f, err := os.Open(*nzbFile)
+		if err != nil {
+			return err
+		}
+		index, err = nzb.Parse(f)
+		f.Close()
+		if err != nil {
+			return withCode(exitUsage, err)
+		}
+	}
+	damaged := 0
+	var all []*yenc.ExtractedFile
+	for _, file := range files {
+		in, err := openInput(file)
+		if err != nil {
+			return err
+		}
+		extract := e.Extract
+		if strings.EqualFold(filepath.Ext(file), ".eml") {
+			extract = e.ExtractMessage
+		}
+		out, err := extract(in)
+		in.Close()
+		if err != nil {
+			return decodeError(fmt.Errorf("%s: %w", file, err))
+		}
+		damaged += report(os.Stdout, out)
+		all = append(all, out...)
+	}
+	if index != nil {
+		found := index.Verify(all, 0)
+		for _, d := range found {
+			fmt.Fprintf(os.Stderr, "nzb: %s\n", d)
+		}
+		if len(found) > 0 && damaged == 0 {
+			return withCode(exitIncomplete, fmt.Errorf("%d differences from the NZB", len(found)))
+		}
+	}
+	if damaged > 0 {
+		return withCode(exitCRC, fmt.Errorf("%d files failed their size or crc checks", damaged))
+	}
+	return nil
+}
+
+// list written files, returning how many were damaged
+func report(w io.Writer, files []*yenc.ExtractedFile) int {
+	damaged := 0
+	for _, f := range files {
+		switch {
+		case f.Skipped:
+			fmt.Fprintf(w, "skipped %s\n", f.Name)
+		case !f.Valid:
+			fmt.Fprintf(w, "damaged %s\n", f.Path)
+			damaged++
+		default:
+			fmt.Fprintf(w, "wrote %s\n", f.Path)
+		}
+	}
+	return damaged
+}
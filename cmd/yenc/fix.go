@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc fix in.yenc [-o out.yenc] [-line n]
+func fix(args []string) error {
+	fs := flag.NewFlagSet("fix", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	line := fs.Int("line", yenc.DefaultLineLength, "encoded chars per line")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc fix in.yenc [-o out.yenc] [-line n]")
+		fmt.Fprintln(os.Stderr, "rewrites line endings, keywords, sizes and crcs in canonical form")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) > 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	in, err := openInput(append(files, "")[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	res, err := (&yenc.Encoding{LineLength: *line}).Rewrite(w, in)
+	if err != nil {
+		f.Close()
+		return decodeError(err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	// data problems can't be fixed, only reported
+	damaged := 0
+	for _, p := range res.Parts {
+		for _, warning := range p.Warnings {
+			fmt.Fprintf(os.Stderr, "%s part %d: %v\n", p.Name, p.Number, warning)
+		}
+		if len(p.Warnings) > 0 {
+			damaged++
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if damaged > 0 {
+		return withCode(exitCRC, fmt.Errorf("%d parts failed their crc checks", damaged))
+	}
+	return nil
+}
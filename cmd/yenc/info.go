@@ -0,0 +1,101 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// infoRecord describes the yenc data in one input
+type infoRecord struct {
+	File string `json:"file"`
+	// why decoding failed, the other fields are then empty
+	Error     string `json:"error"`
+	Multipart bool   `json:"multipart"`
+	// number of parts from the headers, zero if not given
+	Total int `json:"total"`
+	// whole file crc32 from a trailer, empty if not given
+	CRC32 string       `json:"crc32"`
+	Parts []partRecord `json:"parts"`
+}
+
+// yenc info [-json | -ndjson] file...
+func info(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	p := newPrinter(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc info [-json | -ndjson] file...")
+		fmt.Fprintln(os.Stderr, "decodes each file and describes the parts in it without writing them")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	failed := 0
+	var first error
+	for _, file := range files {
+		r, err := describe(file)
+		if err != nil {
+			failed++
+			first = cmp.Or(first, err)
+		}
+		if err := p.print(r, r.text); err != nil {
+			return err
+		}
+	}
+	if err := p.close(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return withCode(exitCode(first), fmt.Errorf("%d of %d files could not be decoded", failed, len(files)))
+	}
+	return nil
+}
+
+// decode file, the error is also recorded in the result
+func describe(file string) (*infoRecord, error) {
+	r := &infoRecord{File: file, Parts: []partRecord{}}
+	in, err := openInput(file)
+	if err != nil {
+		r.Error = err.Error()
+		return r, err
+	}
+	defer in.Close()
+	res, err := yenc.DecodeResult(in, yenc.WithCRCWarnings())
+	if err != nil {
+		r.Error = err.Error()
+		return r, decodeError(err)
+	}
+	defer res.Release()
+	r.Multipart, r.Total = res.Multipart, res.Total
+	if res.FileCRCPresent {
+		r.CRC32 = fmt.Sprintf("%08x", res.FileCRC)
+	}
+	for _, part := range res.Parts {
+		r.Parts = append(r.Parts, newPartRecord(part))
+	}
+	return r, nil
+}
+
+func (r *infoRecord) text(w io.Writer) {
+	if r.Error != "" {
+		fmt.Fprintf(w, "%s: %s\n", r.File, r.Error)
+		return
+	}
+	fmt.Fprintf(w, "%s: %d parts", r.File, len(r.Parts))
+	if r.Total > 0 {
+		fmt.Fprintf(w, " of %d", r.Total)
+	}
+	if r.CRC32 != "" {
+		fmt.Fprintf(w, ", file crc32 %s", r.CRC32)
+	}
+	fmt.Fprintln(w)
+	for _, part := range r.Parts {
+		fmt.Fprintf(w, "  %s\n", part)
+	}
+}
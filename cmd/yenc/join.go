@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// joinRecord is the result of joining the parts of a file
+type joinRecord struct {
+	Name string `json:"name"`
+	// where the file was written, empty if it wasn't
+	Path string `json:"path"`
+	// why joining failed
+	Error string `json:"error"`
+	Size  int64  `json:"size"`
+	// crc32 of the joined data
+	CRC32 string `json:"crc32"`
+	// whether every part was present and passed its checks
+	Valid   bool         `json:"valid"`
+	Parts   []partRecord `json:"parts"`
+	Missing []int        `json:"missing"`
+}
+
+// yenc join [-o out] [-f] [-json | -ndjson] file...
+func join(args []string) error {
+	fs := flag.NewFlagSet("join", flag.ExitOnError)
+	out := fs.String("o", "", "output file, the name from the headers if empty")
+	force := fs.Bool("f", false, "overwrite the output file if it exists")
+	p := newPrinter(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc join [-o out] [-f] [-json | -ndjson] file...")
+		fmt.Fprintln(os.Stderr, "decodes the parts of one file spread over several inputs and writes them in order")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if *out == "-" && p.machine() {
+		return fmt.Errorf("-json and -ndjson can't be used when writing to stdout")
+	}
+	r := &joinRecord{Parts: []partRecord{}, Missing: []int{}}
+	err := r.join(files, *out, *force)
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if *out != "-" {
+		if perr := p.print(r, r.text); perr != nil {
+			return perr
+		}
+		if perr := p.close(); perr != nil {
+			return perr
+		}
+	}
+	if err == nil && !r.Valid {
+		err = withCode(exitCRC, fmt.Errorf("%s failed its size or crc checks", r.Name))
+	}
+	return err
+}
+
+// decode the parts from files and write them to out, replacing an
+// existing file only if force is set
+func (r *joinRecord) join(files []string, out string, force bool) error {
+	var parts []*yenc.Part
+	var fileCRC *uint32
+	total := 0
+	for _, file := range files {
+		in, err := openInput(file)
+		if err != nil {
+			return err
+		}
+		res, err := yenc.DecodeResult(in, yenc.WithCRCWarnings())
+		in.Close()
+		if err != nil {
+			return decodeError(fmt.Errorf("%s: %w", file, err))
+		}
+		parts = append(parts, res.Parts...)
+		total = max(total, res.Total)
+		if res.FileCRCPresent {
+			crc := res.FileCRC
+			fileCRC = &crc
+		}
+	}
+	if len(parts) == 0 {
+		return withCode(exitDecode, yenc.ErrNoParts)
+	}
+	parts = uniqueParts(parts)
+	if names := partNames(parts); len(names) > 1 {
+		return fmt.Errorf("parts of more than one file: %s", strings.Join(names, ", "))
+	}
+	r.Name = parts[0].Name
+	r.Valid = true
+	for _, part := range parts {
+		r.Parts = append(r.Parts, newPartRecord(part))
+		r.Valid = r.Valid && len(part.Warnings) == 0
+	}
+	if parts[0].Number > 0 {
+		r.Missing = append(r.Missing, yenc.MissingParts(parts, total)...)
+	}
+	if len(r.Missing) > 0 {
+		r.Valid = false
+		return withCode(exitIncomplete, fmt.Errorf("%s is missing parts %v", r.Name, r.Missing))
+	}
+	if out == "" {
+		// the name comes from the post, so it must not leave the
+		// working directory or be taken for stdout
+		out = yenc.SanitizeName(r.Name)
+		if out == "-" {
+			out = "./-"
+		}
+	}
+	f, err := createNew(out, force)
+	if err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	w := io.MultiWriter(f, crc)
+	for _, part := range parts {
+		if part.Number > 0 && part.Begin != r.Size+1 {
+			f.Close()
+			return withCode(exitDecode, fmt.Errorf("part %d begins at %d, expected %d", part.Number, part.Begin, r.Size+1))
+		}
+		n, err := w.Write(part.Body)
+		r.Size += int64(n)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if out != "-" {
+		r.Path = out
+	}
+	r.CRC32 = fmt.Sprintf("%08x", crc.Sum32())
+	if fileCRC != nil && *fileCRC != crc.Sum32() {
+		r.Valid = false
+	}
+	return nil
+}
+
+// sort parts into file order, keeping only the first copy of each
+// part number
+func uniqueParts(parts []*yenc.Part) []*yenc.Part {
+	yenc.SortPartsByNumber(parts)
+	var unique []*yenc.Part
+	for i, p := range parts {
+		if i == 0 || p.Number != parts[i-1].Number {
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+// distinct file names of parts, sorted
+func partNames(parts []*yenc.Part) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, p := range parts {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *joinRecord) text(w io.Writer) {
+	switch {
+	case r.Error != "":
+		// reported on stderr by main
+	case r.Valid:
+		fmt.Fprintf(w, "wrote %s, %d bytes from %d parts\n", r.Path, r.Size, len(r.Parts))
+	default:
+		fmt.Fprintf(w, "wrote %s, %d bytes from %d parts, damaged\n", r.Path, r.Size, len(r.Parts))
+		for _, part := range r.Parts {
+			if !part.Valid {
+				fmt.Fprintf(w, "  %s\n", part)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Command yenc decodes, encodes and repairs yenc files.
+//
+// Usage:
+//
+//	yenc <command> [arguments]
+//
+// Commands:
+//
+//	bench	measure encode and decode throughput
+//	check	check yenc files against the spec
+//	decode	decode yenc data to a file or stdout
+//	encode	encode files as yenc
+//	extract	decode the files in yenc data or .eml messages
+//	fix	rewrite a yenc file in canonical form
+//	info	describe the parts in yenc files
+//	join	join the parts of a file from several inputs
+//	recrc	recompute trailer sizes and crcs
+//	resplit	re-encode a file with a different part size
+//	watch	decode files as they appear in a directory
+//
+// Run "yenc <command> -h" for a command's flags. Where a file argument
+// is "-" or missing, stdin or stdout is used. Commands reporting on
+// their inputs take -json or -ndjson for output other programs can read.
+//
+// Exit codes:
+//
+//	0	success
+//	1	any other failure
+//	2	usage error
+//	3	input isn't usable yenc
+//	4	data failed its size or crc checks
+//	5	parts of a file are missing
+//	6	reading or writing files failed
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// each command gets its arguments and reports failure as an error
+var commands = map[string]func(args []string) error{
+	"bench":   bench,
+	"check":   check,
+	"decode":  decode,
+	"encode":  encode,
+	"extract": extract,
+	"fix":     fix,
+	"info":    info,
+	"join":    join,
+	"recrc":   recrc,
+	"resplit": resplit,
+	"watch":   watch,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: yenc <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "commands: bench, check, decode, encode, extract, fix, info, join, recrc, resplit, watch")
+	os.Exit(exitUsage)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "yenc:", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// parse flags that may come before or after the file arguments,
+// returning the file arguments
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var files []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return files
+		}
+		files = append(files, args[0])
+		args = args[1:]
+	}
+}
+
+// open a file argument, "-" or "" meaning stdin
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// create a file argument, "-" or "" meaning stdout
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == "" || name == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+// like createOutput, but refusing to replace an existing file unless
+// force is set
+func createNew(name string, force bool) (io.WriteCloser, error) {
+	if name == "" || name == "-" || force {
+		return createOutput(name)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if os.IsExist(err) {
+		return nil, withCode(exitIO, fmt.Errorf("%s already exists, use -f to overwrite it", name))
+	}
+	return f, err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the commands read stdin, write stdout and exit, so each runs in a
+// copy of the test binary started by run
+func TestMain(m *testing.M) {
+	if os.Getenv("YENC_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// run yenc with args and stdin, returning its stdout and exit code
+func run(t *testing.T, stdin []byte, args ...string) ([]byte, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "YENC_TEST_MAIN=1")
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return stdout.Bytes(), exit.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("could not run yenc %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), 0
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+// encode data in parts of 2000 bytes, returning the part files in order
+func encodeParts(t *testing.T, data []byte) []string {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(in, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, code := run(t, nil, "encode", "-d", filepath.Join(dir, "parts"), "-part-size", "2000", in); code != 0 {
+		t.Fatalf("expected encode to succeed got exit code %d", code)
+	}
+	parts, _ := filepath.Glob(filepath.Join(dir, "parts", "*.yenc"))
+	return parts
+}
+
+func concat(t *testing.T, files ...string) []byte {
+	var b bytes.Buffer
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(data)
+	}
+	return b.Bytes()
+}
+
+func TestEncodeDecodeStdio(t *testing.T) {
+	data := testData(5000)
+	encoded, code := run(t, data, "encode", "-name", "data.bin")
+	if code != 0 || !bytes.HasPrefix(encoded, []byte("=ybegin ")) {
+		t.Fatalf("expected encode to write yenc to stdout, exit code %d", code)
+	}
+	decoded, code := run(t, encoded, "decode")
+	if code != 0 || !bytes.Equal(decoded, data) {
+		t.Errorf("expected decode to write the %d bytes to stdout got %d, exit code %d", len(data), len(decoded), code)
+	}
+}
+
+func TestDecodeVerify(t *testing.T) {
+	parts := encodeParts(t, testData(5000))
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts got %d", len(parts))
+	}
+	out, code := run(t, concat(t, parts...), "decode", "-verify")
+	if code != 0 || bytes.Count(out, []byte("data.bin")) != 3 {
+		t.Errorf("expected all 3 parts to verify got exit code %d:\n%s", code, out)
+	}
+	out, code = run(t, concat(t, parts[0], parts[2]), "decode", "-verify")
+	if code != exitIncomplete || !bytes.Contains(out, []byte("part 2 missing")) {
+		t.Errorf("expected part 2 to be missing got exit code %d:\n%s", code, out)
+	}
+	damaged := concat(t, parts...)
+	i := bytes.Index(damaged, []byte(" pcrc32=")) + len(" pcrc32=")
+	copy(damaged[i:], "deadbeef")
+	if _, code := run(t, damaged, "decode", "-verify"); code != exitCRC {
+		t.Errorf("expected exit code %d for a bad crc got %d", exitCRC, code)
+	}
+}
+
+func TestInfoJSON(t *testing.T) {
+	out, code := run(t, nil, "info", "-json", "../../singlepart_test.yenc")
+	if code != 0 {
+		t.Fatalf("expected info to succeed got exit code %d", code)
+	}
+	// the field names are relied on by scripts
+	var records []map[string]json.RawMessage
+	if err := json.Unmarshal(out, &records); err != nil || len(records) != 1 {
+		t.Fatalf("expected a JSON array of one record got %s (%v)", out, err)
+	}
+	for _, field := range []string{"file", "error", "multipart", "total", "crc32", "parts"} {
+		if _, ok := records[0][field]; !ok {
+			t.Errorf("expected field %q in %s", field, out)
+		}
+	}
+	var parts []map[string]json.RawMessage
+	if err := json.Unmarshal(records[0]["parts"], &parts); err != nil || len(parts) != 1 {
+		t.Fatalf("expected one part got %s (%v)", records[0]["parts"], err)
+	}
+	for _, field := range []string{"name", "part", "begin", "end", "size", "crc32", "valid", "warnings"} {
+		if _, ok := parts[0][field]; !ok {
+			t.Errorf("expected part field %q in %s", field, out)
+		}
+	}
+	if string(parts[0]["name"]) != `"testfile.txt"` || string(parts[0]["size"]) != "584" {
+		t.Errorf("unexpected part %s", records[0]["parts"])
+	}
+
+	// one object per line
+	out, code = run(t, nil, "info", "-ndjson", "../../singlepart_test.yenc", "../../multipart_test.yenc")
+	if lines := bytes.Split(bytes.TrimSpace(out), []byte("\n")); code != 0 || len(lines) != 2 {
+		t.Errorf("expected 2 lines of JSON got exit code %d:\n%s", code, out)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	parts := encodeParts(t, testData(5000))
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		stdin []byte
+		args  []string
+		code  int
+	}{
+		{nil, []string{"nosuchcommand"}, exitUsage},
+		{[]byte("not yenc at all\n"), []string{"decode"}, exitDecode},
+		{nil, []string{"decode", filepath.Join(dir, "missing.yenc")}, exitIO},
+		{nil, []string{"join", "-o", filepath.Join(dir, "out.bin"), parts[0], parts[2]}, exitIncomplete},
+		{nil, []string{"join", "-o", filepath.Join(dir, "out.bin"), parts[0], parts[1], parts[2]}, 0},
+		// join won't replace what it wrote unless forced
+		{nil, []string{"join", "-o", filepath.Join(dir, "out.bin"), parts[0], parts[1], parts[2]}, exitIO},
+		{nil, []string{"join", "-f", "-o", filepath.Join(dir, "out.bin"), parts[0], parts[1], parts[2]}, 0},
+	} {
+		if _, code := run(t, tc.stdin, tc.args...); code != tc.code {
+			t.Errorf("yenc %s: expected exit code %d got %d", strings.Join(tc.args, " "), tc.code, code)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// printer writes a command's results as text, as a single JSON array
+// with -json, or as one JSON object per line as each result is ready
+// with -ndjson. The JSON field names are a stable interface for
+// scripts; add fields but don't rename or remove them.
+type printer struct {
+	w       io.Writer
+	json    bool
+	ndjson  bool
+	records []interface{}
+}
+
+// add the -json and -ndjson flags to fs
+func newPrinter(fs *flag.FlagSet) *printer {
+	p := &printer{w: os.Stdout}
+	fs.BoolVar(&p.json, "json", false, "print results as a JSON array")
+	fs.BoolVar(&p.ndjson, "ndjson", false, "print each result as a line of JSON as soon as it is ready")
+	return p
+}
+
+// whether results are printed as JSON
+func (p *printer) machine() bool {
+	return p.json || p.ndjson
+}
+
+// print a result, text writes its plain form
+func (p *printer) print(record interface{}, text func(w io.Writer)) error {
+	switch {
+	case p.ndjson:
+		return json.NewEncoder(p.w).Encode(record)
+	case p.json:
+		p.records = append(p.records, record)
+		return nil
+	}
+	text(p.w)
+	return nil
+}
+
+// write out the array collected with -json, which is empty rather than
+// null when there were no results
+func (p *printer) close() error {
+	if !p.json || p.ndjson {
+		return nil
+	}
+	if p.records == nil {
+		p.records = []interface{}{}
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p.records)
+}
+
+// partRecord describes one decoded part
+type partRecord struct {
+	Name string `json:"name"`
+	// zero for single part data
+	Number int   `json:"part"`
+	Begin  int64 `json:"begin"`
+	End    int64 `json:"end"`
+	Size   int64 `json:"size"`
+	// crc32 of the decoded data
+	CRC32    string   `json:"crc32"`
+	Valid    bool     `json:"valid"`
+	Warnings []string `json:"warnings"`
+}
+
+func newPartRecord(p *yenc.Part) partRecord {
+	r := partRecord{
+		Name:     p.Name,
+		Number:   p.Number,
+		Begin:    p.Begin,
+		End:      p.End,
+		Size:     int64(len(p.Body)),
+		CRC32:    fmt.Sprintf("%08x", p.PayloadCRC32()),
+		Valid:    len(p.Warnings) == 0,
+		Warnings: []string{},
+	}
+	for _, w := range p.Warnings {
+		r.Warnings = append(r.Warnings, w.Error())
+	}
+	return r
+}
+
+// the text form of a part
+func (r partRecord) String() string {
+	s := fmt.Sprintf("%s: %d bytes, crc32 %s", r.Name, r.Size, r.CRC32)
+	if r.Number > 0 {
+		s = fmt.Sprintf("part %d %s, bytes %d-%d", r.Number, s, r.Begin, r.End)
+	}
+	for _, w := range r.Warnings {
+		s += "\n    " + w
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc recrc in.yenc [-o out.yenc]
+func recrc(args []string) error {
+	fs := flag.NewFlagSet("recrc", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc recrc in.yenc [-o out.yenc]")
+		fmt.Fprintln(os.Stderr, "recomputes trailer sizes and crcs, leaving the encoded data as it is")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) > 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	in, err := openInput(append(files, "")[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	n, err := yenc.RewriteTrailers(f, in)
+	if err != nil {
+		f.Close()
+		return decodeError(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d trailers rewritten\n", n)
+	return f.Close()
+}
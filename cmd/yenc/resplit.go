@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc resplit [-part-size n] [-max-article n] in.yenc outdir
+func resplit(args []string) error {
+	fs := flag.NewFlagSet("resplit", flag.ExitOnError)
+	partSize := fs.String("part-size", "700k", "payload bytes per part, k/m/g suffixes allowed")
+	maxArticle := fs.String("max-article", "", "size parts so each article is at most this many bytes")
+	line := fs.Int("line", yenc.DefaultLineLength, "encoded chars per line")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc resplit [-part-size n] in.yenc outdir")
+		fmt.Fprintln(os.Stderr, "re-encodes every file in in.yenc as name.001.yenc, name.002.yenc, ... in outdir")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) != 2 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	m := &yenc.MultipartEncoder{LineLength: *line}
+	var err error
+	if m.PartSize, err = parseSize(*partSize); err != nil {
+		return withCode(exitUsage, err)
+	}
+	if *maxArticle != "" {
+		if m.MaxArticleSize, err = parseSize(*maxArticle); err != nil {
+			return withCode(exitUsage, err)
+		}
+	}
+	in, err := openInput(files[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	res, err := yenc.DecodeResult(in)
+	if err != nil {
+		return decodeError(err)
+	}
+	if err := os.MkdirAll(files[1], 0777); err != nil {
+		return err
+	}
+	for _, f := range groupFiles(res.Parts) {
+		data := assemble(f)
+		name := f[0].Name
+		err := m.Encode(bytes.NewReader(data), name, int64(len(data)), func(part, total int) (io.Writer, error) {
+			return os.Create(filepath.Join(files[1], fmt.Sprintf("%s.%03d.yenc", filepath.Base(name), part)))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// group parts by file name, keeping the order names first appear in
+func groupFiles(parts []*yenc.Part) [][]*yenc.Part {
+	var files [][]*yenc.Part
+	index := make(map[string]int)
+	for _, p := range parts {
+		i, ok := index[p.Name]
+		if !ok {
+			i = len(files)
+			index[p.Name] = i
+			files = append(files, nil)
+		}
+		files[i] = append(files[i], p)
+	}
+	return files
+}
+
+// put the parts of one file together at their offsets
+func assemble(parts []*yenc.Part) []byte {
+	var size int64
+	for _, p := range parts {
+		size = max(size, max(p.Begin-1, 0)+int64(len(p.Body)))
+	}
+	data := make([]byte, size)
+	for _, p := range parts {
+		copy(data[max(p.Begin-1, 0):], p.Body)
+	}
+	return data
+}
+
+// parse a byte count like 700k or 1g, suffixes are powers of 1024
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	switch strings.ToLower(s[len(s)-1:]) {
+	case "k":
+		mult = 1 << 10
+	case "m":
+		mult = 1 << 20
+	case "g":
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
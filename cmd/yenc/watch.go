@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// yenc watch -in dir -out dir [-quarantine dir] [-interval d] [-once]
+func watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	in := fs.String("in", "", "directory to watch for yenc files")
+	out := fs.String("out", "", "directory decoded files are written to")
+	quarantine := fs.String("quarantine", "", "directory failed inputs are moved to (default in/quarantine)")
+	interval := fs.Duration("interval", 5*time.Second, "time between scans")
+	once := fs.Bool("once", false, "scan twice, one interval apart, then exit")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: yenc watch -in incoming/ -out done/ [-quarantine dir] [-interval 5s]")
+		fmt.Fprintln(os.Stderr, "decodes files appearing in the input directory, assembling multipart sets,")
+		fmt.Fprintln(os.Stderr, "and removes inputs once decoded. Inputs that fail are moved to quarantine.")
+		fs.PrintDefaults()
+	}
+	if parseArgs(fs, args); *in == "" || *out == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if *quarantine == "" {
+		*quarantine = filepath.Join(*in, "quarantine")
+	}
+	for _, dir := range []string{*out, *quarantine} {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+	}
+	w := &watcher{
+		in:         *in,
+		quarantine: *quarantine,
+		extractor:  &yenc.Extractor{Dir: *out, Collision: yenc.CollisionRename},
+		sizes:      make(map[string]int64),
+		sets:       make(map[string]*pendingSet),
+		seen:       make(map[string]bool),
+	}
+	for i := 0; !*once || i < 2; i++ {
+		if i > 0 {
+			time.Sleep(*interval)
+		}
+		if err := w.scan(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watcher decodes the files that appear in a directory
+type watcher struct {
+	in, quarantine string
+	extractor      *yenc.Extractor
+	// sizes seen on the last scan, files are only read once they
+	// have stopped growing
+	sizes map[string]int64
+	// incomplete multipart sets by file name
+	sets map[string]*pendingSet
+	// inputs already added to a set
+	seen map[string]bool
+}
+
+// pendingSet collects the inputs holding the parts of one file
+type pendingSet struct {
+	total  int
+	parts  map[int]bool
+	inputs []string
+}
+
+// look for inputs that have stopped growing and decode them
+func (w *watcher) scan() error {
+	entries, err := os.ReadDir(w.in)
+	if err != nil {
+		return err
+	}
+	sizes := make(map[string]int64)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(w.in, entry.Name())
+		sizes[path] = info.Size()
+		if size, ok := w.sizes[path]; ok && size == info.Size() {
+			w.add(path)
+		}
+	}
+	w.sizes = sizes
+	return nil
+}
+
+// group an input into its set by its headers, and extract the set if
+// that makes it complete
+func (w *watcher) add(path string) {
+	if w.seen[path] {
+		return
+	}
+	w.seen[path] = true
+	infos, err := scanHeaders(path)
+	if err != nil {
+		w.fail([]string{path}, err)
+		return
+	}
+	name := infos[0].Name
+	set := w.sets[name]
+	if set == nil {
+		set = &pendingSet{total: infos[0].Total, parts: make(map[int]bool)}
+		w.sets[name] = set
+	}
+	set.inputs = append(set.inputs, path)
+	for _, info := range infos {
+		set.parts[info.Number] = true
+	}
+	if infos[0].Number > 0 && len(set.parts) < set.total {
+		log.Printf("%s: have %d of %d parts", name, len(set.parts), set.total)
+		return
+	}
+	delete(w.sets, name)
+	w.extract(set.inputs)
+}
+
+// read the headers of the parts in an input, skipping their bodies,
+// which are decoded once the set is complete
+func scanHeaders(path string) ([]yenc.PartInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var infos []yenc.PartInfo
+	_, err = yenc.DecodeResult(f, yenc.WithFilter(func(info yenc.PartInfo) bool {
+		infos = append(infos, info)
+		return false
+	}))
+	// every part having been skipped isn't a failure
+	if len(infos) > 0 && errors.Is(err, yenc.ErrNoParts) {
+		err = nil
+	}
+	return infos, err
+}
+
+// extract the files in a complete set of inputs, removing the inputs
+// when everything decoded cleanly
+func (w *watcher) extract(inputs []string) {
+	sort.Strings(inputs)
+	readers := make([]io.Reader, 0, len(inputs))
+	for _, path := range inputs {
+		f, err := os.Open(path)
+		if err != nil {
+			w.fail(inputs, err)
+			return
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	files, err := w.extractor.Extract(io.MultiReader(readers...))
+	if err == nil {
+		for _, f := range files {
+			if !f.Valid {
+				err = fmt.Errorf("%s failed its size or crc checks", f.Name)
+			}
+		}
+	}
+	if err != nil {
+		w.fail(inputs, err)
+		return
+	}
+	for _, f := range files {
+		log.Printf("wrote %s", f.Path)
+	}
+	for _, path := range inputs {
+		w.forget(path)
+		if err := os.Remove(path); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// move inputs that could not be decoded out of the way
+func (w *watcher) fail(inputs []string, err error) {
+	log.Printf("%s: %v", filepath.Base(inputs[0]), err)
+	for _, path := range inputs {
+		w.forget(path)
+		if err := os.Rename(path, w.quarantinePath(path)); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// where to move path in the quarantine, numbered so as not to replace
+// an earlier input of the same name
+func (w *watcher) quarantinePath(path string) string {
+	base := filepath.Base(path)
+	dst := filepath.Join(w.quarantine, base)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dst); os.IsNotExist(err) {
+			return dst
+		}
+		dst = filepath.Join(w.quarantine, fmt.Sprintf("%s.%d", base, i))
+	}
+}
+
+func (w *watcher) forget(path string) {
+	delete(w.seen, path)
+	delete(w.sizes, path)
+}
@@ -0,0 +1,127 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConformanceFixtures decodes a corpus kept outside the repo. Set
+// YENC_FIXTURES to a directory holding the fixtures and a MANIFEST in
+// sha256sum format listing the sha256 of each fixture's decoded data:
+//
+//	9f86d081884c7d65...  post.yenc
+//	-  truncated.yenc
+//
+// A "-" in place of the sum means the fixture must fail to decode.
+func TestConformanceFixtures(t *testing.T) {
+	dir := os.Getenv("YENC_FIXTURES")
+	if dir == "" {
+		t.Skip("set YENC_FIXTURES to run the conformance fixtures")
+	}
+	manifest, err := os.Open(filepath.Join(dir, "MANIFEST"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manifest.Close()
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		sum, name := fields[0], strings.TrimPrefix(fields[1], "*")
+		t.Run(name, func(t *testing.T) {
+			got, err := fixtureSum(filepath.Join(dir, name))
+			switch {
+			case sum == "-" && err == nil:
+				t.Errorf("expected decode to fail")
+			case sum != "-" && err != nil:
+				t.Errorf("expected to decode: %v", err)
+			case sum != "-" && got != sum:
+				t.Errorf("expected sha256 %s got %s", sum, got)
+			}
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sha256 of a fixture's decoded data in file order
+func fixtureSum(path string) (string, error) {
+	data, err := fixtureData(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// a fixture's decoded data in file order
+func fixtureData(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res, err := DecodeResult(f)
+	if err != nil {
+		return nil, err
+	}
+	SortPartsByBegin(res.Parts)
+	var data []byte
+	for _, p := range res.Parts {
+		data = append(data, p.Body...)
+	}
+	return data, nil
+}
+
+// TestDifferential compares the decoder with a reference decoder over
+// the .yenc files in the repo and in YENC_FIXTURES. Set YENC_REFERENCE
+// to a command that is given a fixture path as its last argument and
+// writes the decoded data to stdout, for example a small wrapper around
+// python's yenc module or rapidyenc.
+func TestDifferential(t *testing.T) {
+	reference := strings.Fields(os.Getenv("YENC_REFERENCE"))
+	if len(reference) == 0 {
+		t.Skip("set YENC_REFERENCE to compare against a reference decoder")
+	}
+	paths, _ := filepath.Glob("*.yenc")
+	if dir := os.Getenv("YENC_FIXTURES"); dir != "" {
+		more, _ := filepath.Glob(filepath.Join(dir, "*.yenc"))
+		paths = append(paths, more...)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			args := append(reference[1:len(reference):len(reference)], path)
+			expected, refErr := exec.Command(reference[0], args...).Output()
+			got, err := fixtureData(path)
+			switch {
+			case refErr != nil && err != nil:
+				// both reject it
+			case refErr != nil:
+				t.Errorf("reference failed (%v) but we decoded %d bytes", refErr, len(got))
+			case err != nil:
+				t.Errorf("reference decoded %d bytes but we failed: %v", len(expected), err)
+			case !bytes.Equal(got, expected):
+				t.Errorf("output differs from reference at byte %d", firstDifference(got, expected))
+			}
+		})
+	}
+}
+
+func firstDifference(a, b []byte) int {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
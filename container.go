@@ -0,0 +1,93 @@
+package yenc
+
+import (
+	"fmt"
+	"io"
+)
+
+// ContainerEntry locates one file in a stream written by a
+// ContainerWriter. Decoding io.NewSectionReader(stream, Offset, Length)
+// gives back just that file.
+type ContainerEntry struct {
+	Name string
+	// bytes of data in the file
+	Size int64
+	// where the file's =ybegin line starts in the stream and the length
+	// of its block up to the end of its =yend line
+	Offset, Length int64
+}
+
+// ContainerWriter writes several files into one stream as single part
+// yenc blocks one after another, the way Extract and DecodeResult read
+// multi-file streams, keeping an index of where each block is. Like
+// zip.Writer, each file is written to the writer Create returns until
+// the next call to Create or Close.
+type ContainerWriter struct {
+	w     *countingWriter
+	line  int
+	cur   *Encoder
+	index []ContainerEntry
+}
+
+// NewContainerWriter returns a ContainerWriter writing to w using
+// StdEncoding
+func NewContainerWriter(w io.Writer) *ContainerWriter {
+	return StdEncoding.NewContainerWriter(w)
+}
+
+// NewContainerWriter returns a ContainerWriter writing to w using this
+// encoding
+func (enc *Encoding) NewContainerWriter(w io.Writer) *ContainerWriter {
+	return &ContainerWriter{w: &countingWriter{w: w}, line: enc.lineLength()}
+}
+
+// Create finishes the previous file and starts the next one, returning
+// the writer that exactly size bytes of name must be written to
+func (c *ContainerWriter) Create(name string, size int64) (io.Writer, error) {
+	if err := c.finish(); err != nil {
+		return nil, err
+	}
+	c.cur = newEncoder(c.w, name, size)
+	c.cur.line = c.line
+	c.index = append(c.index, ContainerEntry{Name: name, Size: size, Offset: c.w.n})
+	return c.cur, nil
+}
+
+// close the current file's encoder and note where its block ended
+func (c *ContainerWriter) finish() error {
+	if c.cur == nil {
+		return nil
+	}
+	e := &c.index[len(c.index)-1]
+	err := c.cur.Close()
+	c.cur = nil
+	if err != nil {
+		return fmt.Errorf("yenc: %s: %w", e.Name, err)
+	}
+	e.Length = c.w.n - e.Offset
+	return nil
+}
+
+// Close finishes the last file. It does not close the underlying
+// writer.
+func (c *ContainerWriter) Close() error {
+	return c.finish()
+}
+
+// Index returns the files written so far, in order. The last entry's
+// Length is only known once the file is finished.
+func (c *ContainerWriter) Index() []ContainerEntry {
+	return append([]ContainerEntry(nil), c.index...)
+}
+
+// counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,60 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestContainerWriter(t *testing.T) {
+	files := map[string][]byte{
+		"a.txt": []byte("first file"),
+		"b.bin": encodeTestData(5000),
+		"c.nfo": {},
+	}
+	names := []string{"a.txt", "b.bin", "c.nfo"}
+	var buf bytes.Buffer
+	c := NewContainerWriter(&buf)
+	for _, name := range names {
+		w, err := c.Create(name, int64(len(files[name])))
+		if err != nil {
+			t.Fatal("expected to create: " + err.Error())
+		}
+		w.Write(files[name])
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal("expected to close: " + err.Error())
+	}
+	index := c.Index()
+	if len(index) != 3 || index[2].Offset+index[2].Length != int64(buf.Len()) {
+		t.Fatalf("unexpected index %+v for %d bytes", index, buf.Len())
+	}
+
+	// the whole stream decodes as separate files
+	res, err := DecodeResult(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if len(res.Parts) != 3 || res.Parts[1].Name != "b.bin" || !bytes.Equal(res.Parts[1].Body, files["b.bin"]) {
+		t.Errorf("unexpected parts %+v", res.Parts)
+	}
+	// and each entry decodes on its own
+	for i, e := range index {
+		p, err := Decode(io.NewSectionReader(bytes.NewReader(buf.Bytes()), e.Offset, e.Length))
+		if err != nil {
+			t.Fatalf("%d: expected to decode: %v", i, err)
+		}
+		if p.Name != names[i] || int64(len(p.Body)) != e.Size || !bytes.Equal(p.Body, files[names[i]]) {
+			t.Errorf("%d: unexpected part %q of %d bytes", i, p.Name, len(p.Body))
+		}
+	}
+}
+
+func TestContainerWriterShortFile(t *testing.T) {
+	c := NewContainerWriter(io.Discard)
+	w, _ := c.Create("a.txt", 10)
+	w.Write([]byte("short"))
+	if _, err := c.Create("b.txt", 0); err == nil {
+		t.Errorf("expected an error for a file shorter than its size")
+	}
+}
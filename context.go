@@ -0,0 +1,71 @@
+package yenc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// DecodeContext is Decode giving up with ctx.Err() once ctx is done.
+// Cancellation is checked before each read of input; inputs with a
+// SetReadDeadline method, such as a net.Conn, also have a read that is
+// already blocked cut short, their deadline being cleared again when
+// the decode ends.
+func DecodeContext(ctx context.Context, input io.Reader, opts ...Option) (*Part, error) {
+	cr := newContextReader(ctx, input)
+	defer cr.stop()
+	return Decode(cr, opts...)
+}
+
+// contextReader fails reads once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+	// r, if its reads can be cut short
+	conn readDeadliner
+	stop func()
+}
+
+func newContextReader(ctx context.Context, r io.Reader) *contextReader {
+	cr := &contextReader{ctx: ctx, r: r, stop: func() {}}
+	if conn, ok := r.(readDeadliner); ok {
+		cr.conn = conn
+		fired := make(chan struct{})
+		// a deadline in the past wakes a blocked read
+		stop := context.AfterFunc(ctx, func() {
+			conn.SetReadDeadline(time.Unix(1, 0))
+			close(fired)
+		})
+		cr.stop = func() {
+			if !stop() {
+				// the deadline outlives the decode, so clear it once
+				// set rather than fail the conn's next reads
+				<-fired
+				conn.SetReadDeadline(time.Time{})
+			}
+		}
+	}
+	return cr
+}
+
+// SetReadDeadline passes t on to the input, so WithTimeout can cut its
+// reads short too
+func (cr *contextReader) SetReadDeadline(t time.Time) error {
+	if cr.conn == nil {
+		return nil
+	}
+	return cr.conn.SetReadDeadline(t)
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := cr.r.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) && cr.ctx.Err() != nil {
+		err = cr.ctx.Err()
+	}
+	return n, err
+}
@@ -0,0 +1,66 @@
+package yenc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDecodeContext(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	if _, err := DecodeContext(context.Background(), bytes.NewReader(raw)); err != nil {
+		t.Errorf("expected to decode: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DecodeContext(ctx, &trickleReader{raw, time.Millisecond}); err != context.Canceled {
+		t.Errorf("expected cancelled error got %v", err)
+	}
+}
+
+func TestDecodeContextConn(t *testing.T) {
+	// a connection that stalls after the header
+	client, server := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go server.Write([]byte("=ybegin line=128 size=3 name=x\r\n"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := DecodeContext(ctx, client); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error got %v", err)
+	}
+	// the connection is usable again once the decode has returned
+	go server.Write([]byte("x"))
+	if _, err := client.Read(make([]byte, 1)); err != nil {
+		t.Errorf("expected the deadline to be cleared got %v", err)
+	}
+}
+
+func TestDecodeContextWithTimeout(t *testing.T) {
+	// a connection that sends nothing at all, under a context that
+	// outlasts the timeout
+	client, server := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := DecodeContext(context.Background(), client, WithTimeout(20*time.Millisecond))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var timeout *TimeoutError
+		if !errors.As(err, &timeout) {
+			t.Errorf("expected timeout error got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the timeout to cut the blocked read short")
+	}
+}
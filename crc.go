@@ -0,0 +1,72 @@
+package yenc
+
+import "hash/crc32"
+
+// CombineCRC32 returns the crc32 of a file from the crcs of its parts,
+// as zlib's crc32_combine does, so a file decoded a part at a time can
+// be checked against the crc32 in its last trailer without hashing its
+// data again. parts must cover the file once each; they are taken in
+// file order whatever order they are given in.
+func CombineCRC32(parts []*Part) uint32 {
+	sorted := append([]*Part(nil), parts...)
+	SortPartsByBegin(sorted)
+	var crc uint32
+	for _, p := range sorted {
+		crc = combineCRC32(crc, p.PayloadCRC32(), p.DecodedLen())
+	}
+	return crc
+}
+
+// the crc32 of a followed by b, given the crc32s of each and the length
+// of b. Appending n zero bits to a is a linear operation on its crc, so
+// it is done by squaring the matrix for one zero bit.
+func combineCRC32(crcA, crcB uint32, lenB int64) uint32 {
+	if lenB <= 0 {
+		return crcA
+	}
+	var even, odd [32]uint32
+	// the operator for one zero bit
+	odd[0] = crc32.IEEE
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	// two zero bits, then four
+	gf2Square(&even, &odd)
+	gf2Square(&odd, &even)
+	// apply the operator for each set bit of the length in bytes
+	for {
+		gf2Square(&even, &odd)
+		if lenB&1 != 0 {
+			crcA = gf2Times(&even, crcA)
+		}
+		if lenB >>= 1; lenB == 0 {
+			break
+		}
+		gf2Square(&odd, &even)
+		if lenB&1 != 0 {
+			crcA = gf2Times(&odd, crcA)
+		}
+		if lenB >>= 1; lenB == 0 {
+			break
+		}
+	}
+	return crcA ^ crcB
+}
+
+func gf2Times(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return sum
+}
+
+func gf2Square(square, mat *[32]uint32) {
+	for n := range square {
+		square[n] = gf2Times(mat, mat[n])
+	}
+}
@@ -0,0 +1,31 @@
+package yenc
+
+import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+)
+
+func TestCombineCRC32(t *testing.T) {
+	data := encodeTestData(10000)
+	var all bytes.Buffer
+	for _, a := range encodeParts(t, &MultipartEncoder{PartSize: 3000}, data) {
+		all.Write(a.Bytes())
+	}
+	res, err := DecodeResult(&all)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	// out of order, and one part streamed without its body
+	parts := []*Part{res.Parts[2], res.Parts[0], res.Parts[3], res.Parts[1]}
+	parts[0].Body = nil
+	if got := CombineCRC32(parts); got != crc32.ChecksumIEEE(data) || got != res.FileCRC {
+		t.Errorf("expected %08x got %08x", res.FileCRC, got)
+	}
+	for _, n := range []int{0, 1, 7, 4096} {
+		a, b := data[:n], data[n:n*2]
+		if got := combineCRC32(crc32.ChecksumIEEE(a), crc32.ChecksumIEEE(b), int64(len(b))); got != crc32.ChecksumIEEE(data[:n*2]) {
+			t.Errorf("combining %d bytes: got %08x", n, got)
+		}
+	}
+}
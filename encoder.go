@@ -0,0 +1,296 @@
+package yenc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+const (
+	// default number of encoded chars per line
+	DefaultLineLength = 128
+	// how much encoded output is buffered before writing it out
+	encodeBufferSize = 32 << 10
+	// block size used by ReadFrom
+	readFromBlockSize = 64 << 10
+)
+
+// output and ReadFrom buffers are reused between encoders so posting
+// lots of parts doesn't churn the allocator
+var (
+	encodeBufPool = sync.Pool{New: func() interface{} {
+		b := make([]byte, 0, encodeBufferSize+2*DefaultLineLength)
+		return &b
+	}}
+	blockPool = sync.Pool{New: func() interface{} {
+		b := make([]byte, readFromBlockSize)
+		return &b
+	}}
+)
+
+var errEncoderClosed = errors.New("yenc: write to closed encoder")
+
+// Encoder writes data in yenc format to an underlying writer. The
+// header is written with the first data, Close writes the trailer.
+type Encoder struct {
+	// lines written before =ybegin and after =yend, such as article
+	// headers, each followed by CRLF. Set them before the first Write.
+	Preamble, Postamble []string
+
+	w    io.Writer
+	name string
+	// size of the whole file
+	size int64
+	// encoded chars per line
+	line int
+	// part info, zero for single part output
+	part, total int
+	begin, end  int64
+	// crc of the whole file, added to the last part's trailer
+	fileCRC    uint32
+	hasFileCRC bool
+	// encoding state
+	wroteHeader bool
+	col         int
+	n           int64
+	crc         uint32
+	// pending output, from encodeBufPool
+	buf *[]byte
+	err error
+}
+
+// NewEncoder returns an Encoder writing a single part file called name
+// to w using StdEncoding. Exactly size bytes must be written before
+// calling Close.
+func NewEncoder(w io.Writer, name string, size int64) *Encoder {
+	return StdEncoding.NewEncoder(w, name, size)
+}
+
+// Encode writes data to w as a single part file called name using
+// StdEncoding, header, escaped lines and crc32 trailer included
+func Encode(w io.Writer, name string, data []byte) error {
+	return StdEncoding.Encode(w, name, data)
+}
+
+func newEncoder(w io.Writer, name string, size int64) *Encoder {
+	return &Encoder{
+		w:    w,
+		name: name,
+		size: size,
+		line: DefaultLineLength,
+		buf:  encodeBufPool.Get().(*[]byte),
+	}
+}
+
+// expected payload size for this part
+func (e *Encoder) partSize() int64 {
+	if e.part > 0 {
+		return e.end - e.begin + 1
+	}
+	return e.size
+}
+
+// append lines to the output, each ended with CRLF
+func appendLines(buf []byte, lines []string) []byte {
+	for _, line := range lines {
+		buf = append(append(buf, line...), '\r', '\n')
+	}
+	return buf
+}
+
+// bytes taken by lines once written
+func linesLen(lines []string) int64 {
+	var n int64
+	for _, line := range lines {
+		n += int64(len(line)) + 2
+	}
+	return n
+}
+
+func (e *Encoder) writeHeader() {
+	*e.buf = appendLines(*e.buf, e.Preamble)
+	if e.part > 0 && e.total > 0 {
+		*e.buf = fmt.Appendf(*e.buf, "=ybegin part=%d total=%d line=%d size=%d name=%s\r\n=ypart begin=%d end=%d\r\n",
+			e.part, e.total, e.line, e.size, e.name, e.begin, e.end)
+	} else if e.part > 0 {
+		*e.buf = fmt.Appendf(*e.buf, "=ybegin part=%d line=%d size=%d name=%s\r\n=ypart begin=%d end=%d\r\n",
+			e.part, e.line, e.size, e.name, e.begin, e.end)
+	} else {
+		*e.buf = fmt.Appendf(*e.buf, "=ybegin line=%d size=%d name=%s\r\n", e.line, e.size, e.name)
+	}
+	e.wroteHeader = true
+}
+
+// Write encodes p
+func (e *Encoder) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.n+int64(len(p)) > e.partSize() {
+		return 0, fmt.Errorf("yenc: write exceeds declared size %d", e.partSize())
+	}
+	if !e.wroteHeader {
+		e.writeHeader()
+	}
+	e.encode(p)
+	return len(p), e.err
+}
+
+// ReadFrom encodes everything read from r in large blocks, so that
+// io.Copy(encoder, file) doesn't depend on the caller's write sizes
+func (e *Encoder) ReadFrom(r io.Reader) (int64, error) {
+	bp := blockPool.Get().(*[]byte)
+	defer blockPool.Put(bp)
+	block := *bp
+	var total int64
+	for {
+		n, err := r.Read(block)
+		if n > 0 {
+			if _, werr := e.Write(block[:n]); werr != nil {
+				return total, werr
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// encode p into the output buffer, flushing full lines as it fills
+func (e *Encoder) encode(p []byte) {
+	e.crc = crc32.Update(e.crc, crc32.IEEETable, p)
+	e.n += int64(len(p))
+	buf := *e.buf
+	for i := 0; i < len(p); i++ {
+		// away from the line edges only the four critical chars need
+		// escaping, so check eight bytes at once and copy them straight
+		// through when none of them is critical
+		if e.col > 0 && e.col+8 < e.line && len(p)-i >= 8 {
+			if w := add42(binary.LittleEndian.Uint64(p[i:])); !hasCritical(w) {
+				buf = binary.LittleEndian.AppendUint64(buf, w)
+				e.col += 8
+				i += 7
+				continue
+			}
+		}
+		c := p[i] + 42
+		if needsEscape(c, e.col, e.line) {
+			buf = append(buf, '=', c+64)
+			e.col += 2
+		} else {
+			buf = append(buf, c)
+			e.col++
+		}
+		if e.col >= e.line {
+			buf = append(buf, '\r', '\n')
+			e.col = 0
+			if len(buf) >= encodeBufferSize {
+				*e.buf = buf
+				e.flush()
+				buf = *e.buf
+			}
+		}
+	}
+	*e.buf = buf
+}
+
+// eight bytes packed in a word
+const (
+	lanes1    = 0x0101010101010101
+	lanesHigh = 0x8080808080808080
+)
+
+// add 42 to each byte of w without carrying between bytes
+func add42(w uint64) uint64 {
+	return ((w &^ lanesHigh) + 42*lanes1) ^ (w & lanesHigh)
+}
+
+// subtract 42 from each byte of w without borrowing between bytes
+func sub42(w uint64) uint64 {
+	return ((w | lanesHigh) - 42*lanes1) ^ ((w ^ ^uint64(42*lanes1)) & lanesHigh)
+}
+
+// whether any byte of w is zero
+func hasZero(w uint64) bool {
+	return (w-lanes1)&^w&lanesHigh != 0
+}
+
+// whether any byte of w is NUL, LF, CR or =
+func hasCritical(w uint64) bool {
+	return hasZero(w) || hasZero(w^('\n'*lanes1)) || hasZero(w^('\r'*lanes1)) || hasZero(w^('='*lanes1))
+}
+
+// whether the encoded char c at column col must be escaped
+func needsEscape(c byte, col, line int) bool {
+	switch c {
+	case 0, '\n', '\r', '=':
+		return true
+	case ' ', '\t':
+		// whitespace at the start or end of a line gets lost
+		return col == 0 || col >= line-1
+	case '.':
+		// a leading dot needs stuffing on NNTP
+		return col == 0
+	}
+	return false
+}
+
+// write out the buffered output
+func (e *Encoder) flush() {
+	if e.err != nil {
+		return
+	}
+	if _, err := e.w.Write(*e.buf); err != nil {
+		e.err = err
+	}
+	*e.buf = (*e.buf)[:0]
+}
+
+// Close finishes the last line and writes the trailer. It does not
+// close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.n != e.partSize() {
+		return fmt.Errorf("yenc: wrote %d bytes but declared size is %d", e.n, e.partSize())
+	}
+	if !e.wroteHeader {
+		e.writeHeader()
+	}
+	buf := *e.buf
+	if e.col > 0 {
+		// the data ended mid line, whitespace there must be escaped
+		if last := len(buf) - 1; buf[last] == ' ' || buf[last] == '\t' {
+			c := buf[last]
+			buf = append(buf[:last], '=', c+64)
+		}
+		buf = append(buf, '\r', '\n')
+	}
+	if e.part > 0 {
+		buf = fmt.Appendf(buf, "=yend size=%d part=%d pcrc32=%08x", e.n, e.part, e.crc)
+		if e.hasFileCRC {
+			buf = fmt.Appendf(buf, " crc32=%08x", e.fileCRC)
+		}
+		buf = append(buf, '\r', '\n')
+	} else {
+		buf = fmt.Appendf(buf, "=yend size=%d crc32=%08x\r\n", e.n, e.crc)
+	}
+	*e.buf = appendLines(buf, e.Postamble)
+	e.flush()
+	err := e.err
+	// hand the buffer back, the encoder can't be used again
+	encodeBufPool.Put(e.buf)
+	e.buf = nil
+	if e.err == nil {
+		e.err = errEncoderClosed
+	}
+	return err
+}
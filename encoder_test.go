@@ -0,0 +1,161 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func encodeTestData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data)
+	return data
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 127, 128, 129, 1000, 100000} {
+		data := encodeTestData(n)
+		// lots of chars that need escaping
+		for i := 0; i < n; i += 7 {
+			data[i] = []byte{0, '\n', '\r', '='}[i%4] + 214
+		}
+		var buf bytes.Buffer
+		e := NewEncoder(&buf, "data.bin", int64(n))
+		if _, err := e.Write(data); err != nil {
+			t.Fatal("expected to write: " + err.Error())
+		}
+		if err := e.Close(); err != nil {
+			t.Fatal("expected to close: " + err.Error())
+		}
+		part, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("expected to decode %d bytes: %v", n, err)
+		}
+		if !bytes.Equal(part.Body, data) || part.Name != "data.bin" {
+			t.Errorf("round trip of %d bytes did not match", n)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	data := encodeTestData(1000)
+	var buf bytes.Buffer
+	if err := (&Encoding{LineLength: 64}).Encode(&buf, "data.bin", data); err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("=ybegin line=64 size=1000 name=data.bin\r\n")) {
+		t.Errorf("unexpected header %q", buf.Bytes()[:40])
+	}
+	part, err := Decode(&buf)
+	if err != nil || !bytes.Equal(part.Body, data) {
+		t.Errorf("round trip did not match (%v)", err)
+	}
+}
+
+func TestEncodeLineEdges(t *testing.T) {
+	// whitespace and dots at the edges of lines must be escaped
+	data := bytes.Repeat([]byte{' ' + 214, '.' - 42, '\t' + 214}, 200)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "ws.bin", int64(len(data)))
+	e.Write(data)
+	if err := e.Close(); err != nil {
+		t.Fatal("expected to close: " + err.Error())
+	}
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\r\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if c := line[0]; c == ' ' || c == '\t' || c == '.' {
+			t.Errorf("line starts with unescaped %q", c)
+		}
+		if c := line[len(line)-1]; c == ' ' || c == '\t' {
+			t.Errorf("line ends with unescaped %q", c)
+		}
+	}
+}
+
+func TestEncoderReadFrom(t *testing.T) {
+	data := encodeTestData(300000)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "big.bin", int64(len(data)))
+	if n, err := io.Copy(e, bytes.NewReader(data)); err != nil || n != int64(len(data)) {
+		t.Fatalf("expected to copy %d bytes got %d (%v)", len(data), n, err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal("expected to close: " + err.Error())
+	}
+	part, err := Decode(&buf)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if !bytes.Equal(part.Body, data) {
+		t.Errorf("decoded data did not match input")
+	}
+}
+
+func TestEncoderSizeMismatch(t *testing.T) {
+	e := NewEncoder(io.Discard, "short.bin", 10)
+	e.Write([]byte("abc"))
+	if err := e.Close(); err == nil {
+		t.Errorf("expected error closing short encoder")
+	}
+	if _, err := e.Write(make([]byte, 8)); err == nil {
+		t.Errorf("expected error writing past declared size")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	data := encodeTestData(700 << 10)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e := NewEncoder(io.Discard, "bench.bin", int64(len(data)))
+		e.Write(data)
+		e.Close()
+	}
+}
+
+// byte at a time reference for the word at a time kernel
+func referenceEncode(data []byte, line int) []byte {
+	var out []byte
+	col := 0
+	for _, b := range data {
+		c := b + 42
+		if needsEscape(c, col, line) {
+			out = append(out, '=', c+64)
+			col += 2
+		} else {
+			out = append(out, c)
+			col++
+		}
+		if col >= line {
+			out = append(out, '\r', '\n')
+			col = 0
+		}
+	}
+	return out
+}
+
+func TestEncodeKernelMatchesReference(t *testing.T) {
+	data := encodeTestData(20000)
+	// sprinkle in chars that need escaping
+	for i := 0; i < len(data); i += 37 {
+		data[i] = []byte{0, '\n', '\r', '=', ' ', '\t', '.'}[i%7] - 42
+	}
+	for _, line := range []int{16, 61, 128, 997} {
+		var buf bytes.Buffer
+		e := NewEncoder(&buf, "k.bin", int64(len(data)))
+		e.line = line
+		// odd sized writes to cross word boundaries
+		for i := 0; i < len(data); i += 13 {
+			e.Write(data[i:min(i+13, len(data))])
+		}
+		e.Close()
+		body := buf.Bytes()[bytes.IndexByte(buf.Bytes(), '\n')+1:]
+		expected := referenceEncode(data, line)
+		if !bytes.HasPrefix(body, expected[:len(expected)-2]) {
+			t.Errorf("line length %d: kernel output differs from reference", line)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package yenc
+
+import (
+	"fmt"
+	"io"
+)
+
+// chance a byte of random data needs escaping anywhere in a line (NUL,
+// LF, CR and =), plus the extra chars escaped at the start (space, tab,
+// dot) and end (space, tab) of a line
+const (
+	randomEscapeRate = 4.0 / 256
+	lineStartEscapes = 3.0 / 256
+	lineEndEscapes   = 2.0 / 256
+)
+
+// Encoding holds the settings for producing yenc output, in the way
+// base64.Encoding does for base64
+type Encoding struct {
+	// encoded chars per line
+	LineLength int
+}
+
+// StdEncoding is the usual encoding with 128 char lines
+var StdEncoding = &Encoding{LineLength: DefaultLineLength}
+
+func (enc *Encoding) lineLength() int {
+	if enc.LineLength > 0 {
+		return enc.LineLength
+	}
+	return DefaultLineLength
+}
+
+// NewEncoder returns an Encoder writing a single part file called name
+// to w using this encoding. Unlike base64 the name and size are needed
+// up front as they go in the =ybegin header.
+func (enc *Encoding) NewEncoder(w io.Writer, name string, size int64) *Encoder {
+	e := newEncoder(w, name, size)
+	e.line = enc.lineLength()
+	return e
+}
+
+// Encode writes data to w as a single part file called name using this
+// encoding
+func (enc *Encoding) Encode(w io.Writer, name string, data []byte) error {
+	e := enc.NewEncoder(w, name, int64(len(data)))
+	if _, err := e.Write(data); err != nil {
+		e.Close()
+		return err
+	}
+	return e.Close()
+}
+
+// EncodedLen returns the most bytes that encoding n bytes of data can
+// produce, line endings included but headers not
+func (enc *Encoding) EncodedLen(n int64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	// if every byte is escaped each line holds half as many bytes
+	perLine := int64(enc.lineLength()+1) / 2
+	lines := (n + perLine - 1) / perLine
+	return 2*n + 2*lines
+}
+
+// TypicalEncodedLen estimates the encoded body length for n bytes of
+// random looking data such as compressed archives
+func (enc *Encoding) TypicalEncodedLen(n int64) int64 {
+	return enc.EstimateEncodedLen(n, randomEscapeRate)
+}
+
+// EstimateEncodedLen estimates the encoded body length for n bytes of
+// data where escapeRate is the fraction of bytes needing an escape
+func (enc *Encoding) EstimateEncodedLen(n int64, escapeRate float64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	line := float64(enc.lineLength())
+	chars := float64(n) * (1 + escapeRate)
+	lines := chars / line
+	chars += lines * (lineStartEscapes + lineEndEscapes)
+	return int64(chars + 2*(lines+1))
+}
+
+// MaxArticleLen returns the most bytes an article holding partSize
+// bytes of a file of fileSize bytes can take, headers included, for
+// checking against a provider's article size limit before posting
+func (enc *Encoding) MaxArticleLen(name string, fileSize, partSize int64) int64 {
+	return articleOverhead(name, fileSize, enc.lineLength()) + enc.EncodedLen(partSize)
+}
+
+// length of the headers and trailer of a part with every number at its
+// widest
+func articleOverhead(name string, size int64, line int) int64 {
+	header := fmt.Sprintf("=ybegin part=%d total=%d line=%d size=%d name=%s\r\n=ypart begin=%d end=%d\r\n",
+		size, size, line, size, name, size, size)
+	trailer := fmt.Sprintf("=yend size=%d part=%d pcrc32=%08x crc32=%08x\r\n", size, size, 0, 0)
+	return int64(len(header) + len(trailer))
+}
+
+// DecodedLen returns the most bytes that n bytes of encoded body can
+// decode to
+func (enc *Encoding) DecodedLen(n int64) int64 {
+	return n
+}
+
+// MinDecodedLen returns the fewest bytes that n bytes of encoded body
+// can decode to, when every byte was escaped
+func (enc *Encoding) MinDecodedLen(n int64) int64 {
+	// each line is at most line+1 chars and a CRLF
+	lines := (n + int64(enc.lineLength()) + 2) / int64(enc.lineLength()+3)
+	if min := (n - 2*lines) / 2; min > 0 {
+		return min
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestEncodingLineLength(t *testing.T) {
+	data := encodeTestData(1000)
+	var buf bytes.Buffer
+	e := (&Encoding{LineLength: 64}).NewEncoder(&buf, "short.bin", int64(len(data)))
+	e.Write(data)
+	if err := e.Close(); err != nil {
+		t.Fatal("expected to close: " + err.Error())
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("=ybegin line=64 ")) {
+		t.Errorf("expected line=64 in header")
+	}
+	lines := bytes.Split(buf.Bytes(), []byte("\r\n"))
+	for _, line := range lines[1 : len(lines)-3] {
+		if len(line) < 64 || len(line) > 65 {
+			t.Errorf("expected 64 or 65 char line got %d", len(line))
+		}
+	}
+}
+
+func TestEncodedLen(t *testing.T) {
+	// everything escaped is the worst case
+	data := bytes.Repeat([]byte{'=' - 42}, 1000)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "x", int64(len(data)))
+	e.Write(data)
+	e.Close()
+	lines := bytes.Split(buf.Bytes(), []byte("\r\n"))
+	body := int64(0)
+	for _, line := range lines[1 : len(lines)-2] {
+		body += int64(len(line)) + 2
+	}
+	if max := StdEncoding.EncodedLen(int64(len(data))); body > max {
+		t.Errorf("encoded body %d larger than EncodedLen %d", body, max)
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	data := encodeTestData(5000)
+	var buf bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &buf, nil
+	})
+	out, err := io.ReadAll(NewDecoder(&buf))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("streamed output did not match input")
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	part, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	out, err := io.ReadAll(NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatal("expected to stream: " + err.Error())
+	}
+	if !bytes.Equal(out, part.Body) {
+		t.Errorf("streamed %d bytes differ from the %d decoded", len(out), len(part.Body))
+	}
+}
+
+func TestDecodeN(t *testing.T) {
+	data := encodeTestData(5000)
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, "data.bin", 5000)
+	enc.ReadFrom(bytes.NewReader(data))
+	enc.Close()
+	// the first stream is cut short, the second is fine
+	short := bytes.NewReader(buf.Bytes()[:buf.Len()/2])
+	streams := []*StreamDecoder{NewDecoder(short), NewDecoder(&buf)}
+	outs := make([]bytes.Buffer, 2)
+	errs := make([]error, 2)
+	for round := 0; round < 20; round++ {
+		for i, s := range streams {
+			if errs[i] != nil {
+				continue
+			}
+			n, err := s.DecodeN(&outs[i], 300)
+			if err == nil && n != 300 {
+				t.Fatalf("stream %d: expected a full budget of 300 got %d", i, n)
+			}
+			errs[i] = err
+		}
+	}
+	if errs[0] != io.ErrUnexpectedEOF || errs[1] != io.EOF {
+		t.Errorf("expected unexpected EOF then EOF got %v and %v", errs[0], errs[1])
+	}
+	if !bytes.Equal(outs[1].Bytes(), data) {
+		t.Errorf("resumed output did not match input")
+	}
+}
+
+func TestNewDecoderCRCMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "x", 3)
+	e.Write([]byte("abc"))
+	e.Close()
+	bad := bytes.Replace(buf.Bytes(), []byte("crc32=352441c2"), []byte("crc32=00000001"), 1)
+	if _, err := io.ReadAll(NewDecoder(bytes.NewReader(bad))); err == nil {
+		t.Errorf("expected crc error from stream")
+	}
+	if _, err := io.ReadAll(NewDecoder(bytes.NewReader(buf.Bytes()[:20]))); err == nil {
+		t.Errorf("expected error from truncated stream")
+	}
+}
+
+func TestEncodedLenEstimates(t *testing.T) {
+	data := encodeTestData(700 << 10)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "random.bin", int64(len(data)))
+	e.Write(data)
+	e.Close()
+	header := int64(bytes.IndexByte(buf.Bytes(), '\n') + 1)
+	trailer := int64(len(buf.Bytes()) - bytes.LastIndex(buf.Bytes(), []byte("=yend")))
+	body := int64(buf.Len()) - header - trailer
+	n := int64(len(data))
+	typical := StdEncoding.TypicalEncodedLen(n)
+	if diff := typical - body; diff < -body/200 || diff > body/200 {
+		t.Errorf("typical estimate %d more than 0.5%% off actual %d", typical, body)
+	}
+	if max := StdEncoding.EncodedLen(n); body > max {
+		t.Errorf("actual %d above worst case %d", body, max)
+	}
+	if min, max := StdEncoding.MinDecodedLen(body), StdEncoding.DecodedLen(body); n < min || n > max {
+		t.Errorf("decoded size %d outside bounds %d-%d", n, min, max)
+	}
+	if max := StdEncoding.MaxArticleLen("random.bin", n, n); int64(buf.Len()) > max {
+		t.Errorf("article %d above MaxArticleLen %d", buf.Len(), max)
+	}
+}
+
+func TestStreamDecoderReset(t *testing.T) {
+	articles := make([][]byte, 3)
+	for i := range articles {
+		var buf bytes.Buffer
+		if err := Encode(&buf, fmt.Sprintf("%d.bin", i), encodeTestData(1000*(i+1))); err != nil {
+			t.Fatal(err)
+		}
+		articles[i] = buf.Bytes()
+	}
+	// leave the first one half read, Reset must drop what is left
+	s := NewDecoder(bytes.NewReader(articles[0]))
+	if _, err := s.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	for i, raw := range articles {
+		s.Reset(bytes.NewReader(raw))
+		out, err := io.ReadAll(s)
+		if err != nil {
+			t.Fatalf("article %d: %v", i, err)
+		}
+		if !bytes.Equal(out, encodeTestData(1000*(i+1))) {
+			t.Errorf("article %d decoded wrongly after Reset", i)
+		}
+	}
+	// only the headers should cost anything, not the read buffer
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range 10 {
+		s.Reset(bytes.NewReader(articles[2]))
+		io.Copy(io.Discard, s)
+	}
+	runtime.ReadMemStats(&after)
+	if n := (after.TotalAlloc - before.TotalAlloc) / 10; n > readBufferSize/4 {
+		t.Errorf("expected Reset to reuse buffers got %d bytes allocated per article", n)
+	}
+}
@@ -0,0 +1,427 @@
+package yenc
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CollisionPolicy decides what happens when an extracted file would
+// take a name that is already in use
+type CollisionPolicy int
+
+const (
+	// fail the extraction (the default)
+	CollisionError CollisionPolicy = iota
+	// replace the existing file
+	CollisionOverwrite
+	// write to foo.1.ext, foo.2.ext, ... instead
+	CollisionRename
+	// keep whichever file passed its crc checks, preferring the
+	// existing one when both did
+	CollisionKeepValid
+)
+
+// Checksum names a hash that can be computed over extracted files
+type Checksum string
+
+const (
+	MD5    Checksum = "md5"
+	SHA256 Checksum = "sha256"
+)
+
+func (c Checksum) new() (hash.Hash, error) {
+	switch c {
+	case MD5:
+		return md5.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("yenc: unknown checksum %q", string(c))
+}
+
+// Extractor decodes yenc data and writes the files it contains into
+// a directory
+type Extractor struct {
+	// directory files are written to, under their names passed through
+	// SanitizeName
+	Dir string
+	// what to do when two files share a name
+	Collision CollisionPolicy
+	// checksums computed for each file while it is written
+	Checksums []Checksum
+	// write a foo.ext.sha256 style sidecar per file and checksum
+	Sidecars bool
+	// if set, the name of a manifest listing the checksums of all
+	// written files, created in Dir. Sidecars and the manifest are
+	// subject to the collision policy like any file.
+	Manifest string
+	// rename files whose extension contradicts their detected type,
+	// eg a .bin that is really a rar
+	FixExtensions bool
+	// options for the decoder, eg WithNameGlob to extract only some files
+	Options []Option
+	// where files are assembled before being moved into Dir, such as a
+	// fast scratch disk. By default they are assembled in Dir itself so
+	// the final rename is atomic; from another volume they are copied
+	// into Dir before the rename.
+	TempDir string
+	// if set, called before each file is written to give its real
+	// name, or "" to keep the one from its headers. This is where the
+	// names of obfuscated posts can be recovered, eg by matching the
+	// file's data against the hashes in PAR2 metadata, see
+	// ExtractedFile.ReadAt. An error stops the extraction.
+	ResolveName func(f *ExtractedFile) (string, error)
+}
+
+// ExtractedFile describes a file produced by Extract
+type ExtractedFile struct {
+	// filename from yenc header
+	Name string
+	// where the data was written, empty if it was skipped
+	Path string
+	// number of decoded bytes
+	Size int64
+	// whether all parts passed their size and crc checks
+	Valid bool
+	// skipped because of the collision policy
+	Skipped bool
+	// hex encoded checksums of the written data
+	Checksums map[Checksum]string
+	// type sniffed from the start of the file
+	DetectedType ContentType
+	// name used instead of Name when FixExtensions corrected it
+	FixedName string
+	// name used instead of Name when given by Extractor.ResolveName
+	ResolvedName string
+	// the decoded parts making up the file
+	parts []*Part
+}
+
+// Parts returns the decoded parts making up the file
+func (f *ExtractedFile) Parts() []*Part {
+	return f.parts
+}
+
+// ReadAt reads the file's decoded data, such as for hashing it in
+// Extractor.ResolveName. Gaps left by missing parts read as zeros.
+func (f *ExtractedFile) ReadAt(p []byte, off int64) (int, error) {
+	var end int64
+	for _, part := range f.parts {
+		end = max(end, part.offset()+int64(len(part.Body)))
+	}
+	if off >= end {
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), end-off)
+	clear(p[:n])
+	for _, part := range f.parts {
+		start := part.offset()
+		lo, hi := max(start, off), min(start+int64(len(part.Body)), off+n)
+		if lo < hi {
+			copy(p[lo-off:hi-off], part.Body[lo-start:hi-start])
+		}
+	}
+	if n < int64(len(p)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// where the part's data starts in the file
+func (p *Part) offset() int64 {
+	return max(p.Begin-1, 0)
+}
+
+// Extract decodes every part in input, groups the parts into files
+// by name and writes each file into e.Dir. Files failing validation are
+// still written but are reported as not Valid.
+func (e *Extractor) Extract(input io.Reader) ([]*ExtractedFile, error) {
+	s := e.newFileSet()
+	if err := s.collect(newDecoder(input, s.opts...)); err != nil {
+		return nil, err
+	}
+	return s.files, e.writeFiles(s.files)
+}
+
+// write out decoded files according to the extractor's settings
+func (e *Extractor) writeFiles(files []*ExtractedFile) error {
+	// paths written by this run and whether their content was valid
+	written := make(map[string]*ExtractedFile)
+	tracer := tracerOf(e.Options)
+	for _, f := range files {
+		if e.ResolveName != nil {
+			name, err := e.ResolveName(f)
+			if err != nil {
+				return err
+			}
+			if name != "" && name != f.Name {
+				f.ResolvedName = name
+			}
+		}
+		// a resolved name is the real one, whatever the data looks like
+		if e.FixExtensions && f.ResolvedName == "" {
+			if name := fixExtension(f.Name, f.DetectedType); name != f.Name {
+				f.FixedName = name
+			}
+		}
+		path, err := e.target(f, written)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			f.Skipped = true
+			continue
+		}
+		span := startSpan(tracer, "yenc.assemble")
+		span.SetAttribute(attrName, f.Name)
+		span.SetAttribute(attrBytes, f.Size)
+		err = f.write(path, e.tempDir(path), e.Checksums)
+		span.End(err)
+		if err != nil {
+			return err
+		}
+		if e.Sidecars {
+			if err := e.writeSidecars(f, written); err != nil {
+				return err
+			}
+		}
+		if prev, ok := written[path]; ok {
+			prev.Path = ""
+			prev.Skipped = true
+		}
+		written[path] = f
+	}
+	if e.Manifest != "" {
+		return e.writeManifest(files, written)
+	}
+	return nil
+}
+
+// fileSet groups decoded parts into files. A repeated part number for
+// a name starts a new file.
+type fileSet struct {
+	files  []*ExtractedFile
+	latest map[string]*ExtractedFile
+	// decoder options
+	opts []Option
+}
+
+func (e *Extractor) newFileSet() *fileSet {
+	return &fileSet{latest: make(map[string]*ExtractedFile), opts: e.Options}
+}
+
+// add every part from d to the set
+func (s *fileSet) collect(d *decoder) error {
+	for {
+		p, err := d.next()
+		if p == nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		s.add(p, d.multipart, err)
+	}
+}
+
+// add a part, err is the result of validating it
+func (s *fileSet) add(p *Part, multipart bool, err error) {
+	f := s.latest[p.Name]
+	if f == nil || !multipart || f.hasPart(p.Number) {
+		f = &ExtractedFile{Name: p.Name, Valid: true}
+		s.latest[p.Name] = f
+		s.files = append(s.files, f)
+	}
+	f.parts = append(f.parts, p)
+	f.Size += int64(len(p.Body))
+	if p.DetectedType != TypeUnknown {
+		f.DetectedType = p.DetectedType
+	}
+	f.Valid = f.Valid && err == nil
+}
+
+func (f *ExtractedFile) hasPart(n int) bool {
+	for _, p := range f.parts {
+		if p.Number == n {
+			return true
+		}
+	}
+	return false
+}
+
+// pick the path for f according to the collision policy, an empty
+// path means the file should not be written
+func (e *Extractor) target(f *ExtractedFile, written map[string]*ExtractedFile) (string, error) {
+	return e.resolve(filepath.Join(e.Dir, SanitizeName(f.outputName())), f.Valid, written)
+}
+
+// pick where to write a file wanting path, valid saying whether its
+// content passed its checks
+func (e *Extractor) resolve(path string, valid bool, written map[string]*ExtractedFile) (string, error) {
+	if !exists(path) {
+		return path, nil
+	}
+	switch e.Collision {
+	case CollisionOverwrite:
+		return path, nil
+	case CollisionRename:
+		ext := filepath.Ext(path)
+		base := strings.TrimSuffix(path, ext)
+		for i := 1; ; i++ {
+			if p := base + "." + strconv.Itoa(i) + ext; !exists(p) {
+				return p, nil
+			}
+		}
+	case CollisionKeepValid:
+		// files we did not write are assumed to be good
+		if prev, ok := written[path]; ok && !prev.Valid && valid {
+			return path, nil
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("yenc: refusing to overwrite existing file %s", path)
+}
+
+// the name f is written under
+func (f *ExtractedFile) outputName() string {
+	switch {
+	case f.ResolvedName != "":
+		return f.ResolvedName
+	case f.FixedName != "":
+		return f.FixedName
+	}
+	return f.Name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// directory the file for path is assembled in
+func (e *Extractor) tempDir(path string) string {
+	if e.TempDir != "" {
+		return e.TempDir
+	}
+	return filepath.Dir(path)
+}
+
+// most missing data before a part that is written as a hole, so a
+// hostile =ypart begin can't have the checksums run over terabytes of
+// zeros
+const maxHole = 4 << 30
+
+// write the parts of f to path in offset order via a temp file in
+// tempDir, computing checksums on the way through. Gaps left by
+// missing parts are hashed as the zeros they read as, and data two
+// parts overlap on is taken from the one starting first.
+func (f *ExtractedFile) write(path, tempDir string, checksums []Checksum) error {
+	hashes := make([]hash.Hash, len(checksums))
+	writers := make([]io.Writer, len(checksums))
+	for i, c := range checksums {
+		h, err := c.new()
+		if err != nil {
+			return err
+		}
+		hashes[i], writers[i] = h, h
+	}
+	out, err := createTemp(tempDir)
+	if err != nil {
+		return err
+	}
+	defer out.discard()
+	SortPartsByBegin(f.parts)
+	hw := io.MultiWriter(writers...)
+	var end int64
+	for _, p := range f.parts {
+		off, body := end, p.Body
+		if p.Begin > 0 {
+			off = p.Begin - 1
+		}
+		if off < end {
+			body = body[min(end-off, int64(len(body))):]
+			off = end
+		}
+		if hole := off - end; hole > maxHole {
+			return fmt.Errorf("yenc: part %d of %s starts %d bytes past the data before it, over the limit of %d", p.Number, f.Name, hole, int64(maxHole))
+		}
+		if _, err := out.WriteAt(body, off); err != nil {
+			return err
+		}
+		if len(writers) > 0 {
+			writeZeros(hw, off-end)
+			hw.Write(body)
+		}
+		end = off + int64(len(body))
+	}
+	if err := out.commit(path); err != nil {
+		return err
+	}
+	f.Path = path
+	f.Checksums = make(map[Checksum]string)
+	for i, c := range checksums {
+		f.Checksums[c] = hex.EncodeToString(hashes[i].Sum(nil))
+	}
+	return nil
+}
+
+// hash n zero bytes, writes to hashes never fail
+func writeZeros(w io.Writer, n int64) {
+	var zeros [32 << 10]byte
+	for n > 0 {
+		m := min(n, int64(len(zeros)))
+		w.Write(zeros[:m])
+		n -= m
+	}
+}
+
+// write foo.ext.sha256 files in the format used by sha256sum
+func (e *Extractor) writeSidecars(f *ExtractedFile, written map[string]*ExtractedFile) error {
+	for c, sum := range f.Checksums {
+		path, err := e.resolve(f.Path+"."+string(c), f.Valid, written)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			continue
+		}
+		line := sum + "  " + filepath.Base(f.Path) + "\n"
+		if err := os.WriteFile(path, []byte(line), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write one BSD style "SHA256 (name) = sum" line per file and checksum,
+// renamed files are noted in a comment
+func (e *Extractor) writeManifest(files []*ExtractedFile, written map[string]*ExtractedFile) error {
+	var b strings.Builder
+	for _, f := range files {
+		if f.Path == "" {
+			continue
+		}
+		switch {
+		case f.ResolvedName != "":
+			fmt.Fprintf(&b, "# %s renamed to %s\n", f.Name, filepath.Base(f.Path))
+		case f.FixedName != "":
+			fmt.Fprintf(&b, "# %s renamed to %s (detected %s)\n", f.Name, filepath.Base(f.Path), f.DetectedType)
+		}
+		for _, c := range e.Checksums {
+			fmt.Fprintf(&b, "%s (%s) = %s\n", strings.ToUpper(string(c)), filepath.Base(f.Path), f.Checksums[c])
+		}
+	}
+	path, err := e.resolve(filepath.Join(e.Dir, e.Manifest), true, written)
+	if err != nil || path == "" {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), 0666)
+}
@@ -0,0 +1,250 @@
+package yenc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func extractFixture(t *testing.T) []byte {
+	good, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	return good
+}
+
+func TestExtractCollisionError(t *testing.T) {
+	good := extractFixture(t)
+	e := &Extractor{Dir: t.TempDir()}
+	files, err := e.Extract(bytes.NewReader(good))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if len(files) != 1 || !files[0].Valid || files[0].Size != 584 {
+		t.Fatalf("unexpected extraction result %+v", files)
+	}
+	if _, err := e.Extract(bytes.NewReader(good)); err == nil {
+		t.Errorf("expected collision error on second extract")
+	}
+}
+
+func TestExtractCollisionRename(t *testing.T) {
+	good := extractFixture(t)
+	e := &Extractor{Dir: t.TempDir(), Collision: CollisionRename}
+	files, err := e.Extract(bytes.NewReader(append(append([]byte{}, good...), good...)))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files got %d", len(files))
+	}
+	if name := filepath.Base(files[1].Path); name != "testfile.1.txt" {
+		t.Errorf("expected renamed file testfile.1.txt got %s", name)
+	}
+}
+
+func TestExtractCollisionKeepValid(t *testing.T) {
+	good := extractFixture(t)
+	bad := bytes.Replace(good, []byte("crc32=ded29f4f"), []byte("crc32=00000001"), 1)
+	for _, input := range [][]byte{
+		append(append([]byte{}, good...), bad...),
+		append(append([]byte{}, bad...), good...),
+	} {
+		e := &Extractor{Dir: t.TempDir(), Collision: CollisionKeepValid}
+		files, err := e.Extract(bytes.NewReader(input))
+		if err != nil {
+			t.Fatal("expected to extract: " + err.Error())
+		}
+		for _, f := range files {
+			if f.Valid == f.Skipped {
+				t.Errorf("expected only the valid file to be kept, got %+v", f)
+			}
+		}
+	}
+}
+
+func TestExtractSanitizesNames(t *testing.T) {
+	input := bytes.Replace(extractFixture(t), []byte("name=testfile.txt"), []byte("name=../sub/test?file.txt"), 1)
+	e := &Extractor{Dir: t.TempDir()}
+	files, err := e.Extract(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if expected := filepath.Join(e.Dir, "sub_test_file.txt"); files[0].Path != expected {
+		t.Errorf("expected %s got %s", expected, files[0].Path)
+	}
+}
+
+func TestExtractChecksums(t *testing.T) {
+	e := &Extractor{
+		Dir:       t.TempDir(),
+		Checksums: []Checksum{SHA256, MD5},
+		Sidecars:  true,
+		Manifest:  "MANIFEST",
+	}
+	files, err := e.Extract(bytes.NewReader(extractFixture(t)))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	data, err := os.ReadFile(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", sha256.Sum256(data))
+	if files[0].Checksums[SHA256] != sum {
+		t.Errorf("expected sha256 %s got %s", sum, files[0].Checksums[SHA256])
+	}
+	sidecar, err := os.ReadFile(files[0].Path + ".sha256")
+	if err != nil || string(sidecar) != sum+"  testfile.txt\n" {
+		t.Errorf("unexpected sidecar %q (%v)", sidecar, err)
+	}
+	manifest, err := os.ReadFile(filepath.Join(e.Dir, "MANIFEST"))
+	if err != nil || !bytes.Contains(manifest, []byte("SHA256 (testfile.txt) = "+sum)) {
+		t.Errorf("unexpected manifest %q (%v)", manifest, err)
+	}
+}
+
+func TestExtractChecksumsCollision(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"testfile.txt.sha256", "MANIFEST"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("precious"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := &Extractor{Dir: dir, Checksums: []Checksum{SHA256}, Sidecars: true, Manifest: "MANIFEST"}
+	if _, err := e.Extract(bytes.NewReader(extractFixture(t))); err == nil {
+		t.Error("expected an existing sidecar to be refused")
+	}
+	e.Dir, e.Collision = t.TempDir(), CollisionRename
+	for _, name := range []string{"testfile.txt.sha256", "MANIFEST"} {
+		if err := os.WriteFile(filepath.Join(e.Dir, name), []byte("precious"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := e.Extract(bytes.NewReader(extractFixture(t))); err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	for _, name := range []string{"testfile.txt.sha256", "MANIFEST"} {
+		if data, err := os.ReadFile(filepath.Join(e.Dir, name)); err != nil || string(data) != "precious" {
+			t.Errorf("expected %s to be left alone got %q (%v)", name, data, err)
+		}
+	}
+	for _, name := range []string{"testfile.txt.1.sha256", "MANIFEST.1"} {
+		if _, err := os.Stat(filepath.Join(e.Dir, name)); err != nil {
+			t.Errorf("expected renamed %s: %v", name, err)
+		}
+	}
+}
+
+func TestExtractChecksumsWithGap(t *testing.T) {
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 1000}, encodeTestData(3000))
+	var input bytes.Buffer
+	input.Write(articles[0].Bytes())
+	input.Write(articles[2].Bytes())
+	e := &Extractor{Dir: t.TempDir(), Checksums: []Checksum{SHA256}}
+	files, err := e.Extract(&input)
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	data, err := os.ReadFile(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := fmt.Sprintf("%x", sha256.Sum256(data)); len(data) != 3000 || files[0].Checksums[SHA256] != sum {
+		t.Errorf("expected sha256 %s of the %d bytes written got %s", sum, len(data), files[0].Checksums[SHA256])
+	}
+}
+
+func TestExtractHostileOffset(t *testing.T) {
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 1000}, encodeTestData(2000))
+	far := strings.Replace(articles[1].String(), "begin=1001 end=2000", "begin=1099511627777 end=1099511628776", 1)
+	e := &Extractor{Dir: t.TempDir(), Checksums: []Checksum{SHA256}}
+	if _, err := e.Extract(io.MultiReader(articles[0], strings.NewReader(far))); err == nil {
+		t.Error("expected a part a terabyte in to be refused")
+	}
+}
+
+func TestExtractFixExtensions(t *testing.T) {
+	f, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	input := bytes.Replace(f, []byte("name=joystick.jpg"), []byte("name=joystick.bin"), 1)
+	e := &Extractor{Dir: t.TempDir(), FixExtensions: true, Manifest: "MANIFEST"}
+	files, err := e.Extract(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if files[0].FixedName != "joystick.jpg" || filepath.Base(files[0].Path) != "joystick.jpg" {
+		t.Errorf("expected file to be renamed to joystick.jpg got %+v", files[0])
+	}
+	manifest, _ := os.ReadFile(filepath.Join(e.Dir, "MANIFEST"))
+	if !bytes.Contains(manifest, []byte("joystick.bin renamed to joystick.jpg")) {
+		t.Errorf("expected rename in manifest got %q", manifest)
+	}
+}
+
+func TestExtractResolveName(t *testing.T) {
+	f, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	input := bytes.Replace(f, []byte("name=joystick.jpg"), []byte("name=a81c3e0f92"), 1)
+	// real names by the hash of their data, as PAR2 metadata gives them
+	real := make(map[[sha256.Size]byte]string)
+	e := &Extractor{Dir: t.TempDir(), FixExtensions: true, Manifest: "MANIFEST"}
+	e.ResolveName = func(f *ExtractedFile) (string, error) {
+		h := sha256.New()
+		if _, err := io.Copy(h, io.NewSectionReader(f, 0, f.Size)); err != nil {
+			return "", err
+		}
+		return real[[sha256.Size]byte(h.Sum(nil))], nil
+	}
+	files, err := e.Extract(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	data, _ := os.ReadFile(files[0].Path)
+	real[sha256.Sum256(data)] = "joystick-real.jpg"
+
+	e.Dir = t.TempDir()
+	files, err = e.Extract(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if files[0].ResolvedName != "joystick-real.jpg" || files[0].FixedName != "" || filepath.Base(files[0].Path) != "joystick-real.jpg" {
+		t.Errorf("expected file to be written as joystick-real.jpg got %+v", files[0])
+	}
+	manifest, _ := os.ReadFile(filepath.Join(e.Dir, "MANIFEST"))
+	if !bytes.Contains(manifest, []byte("a81c3e0f92 renamed to joystick-real.jpg")) {
+		t.Errorf("expected rename in manifest got %q", manifest)
+	}
+}
+
+func TestExtractWithNameGlob(t *testing.T) {
+	var buf bytes.Buffer
+	for _, name := range []string{"release.nfo", "release.rar", "release.r00"} {
+		e := NewEncoder(&buf, name, 3)
+		e.Write([]byte("abc"))
+		e.Close()
+	}
+	e := &Extractor{Dir: t.TempDir(), Options: []Option{WithNameGlob("*.nfo")}}
+	files, err := e.Extract(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if len(files) != 1 || files[0].Name != "release.nfo" {
+		t.Errorf("expected only release.nfo got %+v", files)
+	}
+	e = &Extractor{Dir: t.TempDir(), Options: []Option{WithNameRegexp(regexp.MustCompile(`\.r(ar|\d\d)$`))}}
+	if files, err := e.Extract(bytes.NewReader(buf.Bytes())); err != nil || len(files) != 2 {
+		t.Errorf("expected the two rar volumes got %d files (%v)", len(files), err)
+	}
+}
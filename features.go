@@ -0,0 +1,63 @@
+package yenc
+
+// Capabilities describes what this build of the package supports, for
+// programs that pick behaviour or report diagnostics at runtime
+type Capabilities struct {
+	// yEnc spec versions whose output is read and written
+	SpecVersions []string
+	// keywords understood on each header and trailer line
+	Keywords map[string][]string
+	// optional behaviours by name, and whether they are supported
+	Extensions map[string]bool
+	// the routine doing the bulk of the work for "encode" and "decode".
+	// There are no SIMD kernels; "swar64" works on eight bytes held in
+	// a uint64.
+	Kernels map[string]string
+}
+
+// names of the extensions reported by Features
+const (
+	// NNTP dot-stuffing undone by the decoder, see WithNNTP
+	ExtDotUnstuffing = "dot-unstuffing"
+	// =ybegin2 headers from the yenc 2 draft, see WithYEnc2
+	ExtYbegin2 = "ybegin2"
+	// a crc32 given on the =ybegin line
+	ExtHeaderCRC = "crc-in-header"
+	// several files one after another in one stream
+	ExtMultiFile = "multi-file"
+	// =ybegin found after junk on the same line, see WithResync
+	ExtResync = "resync"
+	// missing =ypart offsets worked out, see WithInferOffsets
+	ExtInferOffsets = "infer-offsets"
+	// CRC-64 of decoded data, see WithCRC64
+	ExtCRC64 = "crc64"
+)
+
+// Features reports the spec versions, keywords, extensions and kernels
+// supported by this build
+func Features() *Capabilities {
+	return &Capabilities{
+		SpecVersions: []string{SpecVersion, "2 draft"},
+		Keywords: map[string][]string{
+			"=ybegin":  {"part", "total", "line", "size", "name"},
+			"=ypart":   {"begin", "end"},
+			"=yend":    {"size", "part", "pcrc32", "crc32"},
+			"=ybegin2": {"part", "total", "line", "size", "name"},
+			"=ypart2":  {"begin", "end"},
+			"=yend2":   {"size", "part", "pcrc32", "crc32"},
+		},
+		Extensions: map[string]bool{
+			ExtDotUnstuffing: true,
+			ExtYbegin2:       true,
+			ExtHeaderCRC:     false,
+			ExtMultiFile:     true,
+			ExtResync:        true,
+			ExtInferOffsets:  true,
+			ExtCRC64:         true,
+		},
+		Kernels: map[string]string{
+			"encode": "swar64",
+			"decode": "swar64",
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package yenc
+
+import "testing"
+
+func TestFeatures(t *testing.T) {
+	f := Features()
+	if !f.Extensions[ExtResync] || !f.Extensions[ExtYbegin2] || f.Extensions[ExtHeaderCRC] {
+		t.Errorf("unexpected extensions %v", f.Extensions)
+	}
+	if len(f.Keywords["=yend"]) == 0 || f.Kernels["decode"] == "" {
+		t.Errorf("expected keywords and kernels got %+v", f)
+	}
+	// each call gets its own copy
+	f.Extensions[ExtHeaderCRC] = true
+	if Features().Extensions[ExtHeaderCRC] {
+		t.Error("expected changes not to leak between calls")
+	}
+}
@@ -0,0 +1,53 @@
+package yenc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// fingerprintVersion prefixes every fingerprint
+const fingerprintVersion = "yf1"
+
+// Fingerprint returns a key identifying the part's content for dedup
+// stores, of the form "yf1-" followed by 64 hex digits. It covers the
+// name from the header, part number, decoded size, crc32 and sha256 of
+// the decoded data, all taken from the data rather than the trailer so
+// copies of a segment fetched from different servers agree.
+//
+// A part whose body wasn't kept, or has been released, needs to have
+// been decoded WithFingerprints; without it Fingerprint returns "".
+//
+// Fingerprints are safe to persist: the value for a given part will not
+// change between versions of this package. Should the scheme ever need
+// to change, the new one will use a different prefix.
+func (p *Part) Fingerprint() string {
+	var payload []byte
+	switch {
+	case p.Body != nil:
+		sum := sha256.Sum256(p.Body)
+		payload = sum[:]
+	case p.sha256Hash != nil:
+		payload = p.sha256Hash.Sum(nil)
+	default:
+		return ""
+	}
+	// names changed by WithSanitizedNames or WithNameCharset would
+	// give another fingerprint for the same data
+	name := p.RawName
+	if name == "" {
+		name = p.Name
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%d\n%d\n%08x\n%x\n", fingerprintVersion,
+		name, p.Number, p.DecodedLen(), p.PayloadCRC32(), payload)
+	return fingerprintVersion + "-" + hex.EncodeToString(h.Sum(nil))
+}
+
+// WithFingerprints hashes each part's data with sha256 while decoding,
+// so Part.Fingerprint works for parts whose body isn't kept.
+func WithFingerprints() Option {
+	return func(d *decoder) {
+		d.fingerprints = true
+	}
+}
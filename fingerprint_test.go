@@ -0,0 +1,49 @@
+package yenc
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	part, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	// persisted fingerprints depend on this never changing
+	const expected = "yf1-84bdfc80e5083d3c991524e2f314de79463d00e0b122795ac51b9e189b93e3bf"
+	if fp := part.Fingerprint(); fp != expected {
+		t.Errorf("expected fingerprint %s got %s", expected, fp)
+	}
+	// the trailer is not part of it
+	other, _ := Decode(bytes.NewReader(bytes.Replace(raw, []byte(" pcrc32=bfae5c0b"), nil, 1)))
+	if other.Fingerprint() != part.Fingerprint() {
+		t.Errorf("expected fingerprint to ignore the trailer crc")
+	}
+	// nor are names changed by options
+	pathed := bytes.Replace(raw, []byte("name=joystick.jpg"), []byte("name=../joystick.jpg"), 1)
+	plain, _ := Decode(bytes.NewReader(pathed))
+	sanitized, _ := Decode(bytes.NewReader(pathed), WithSanitizedNames())
+	if sanitized.Name == plain.Name || sanitized.Fingerprint() != plain.Fingerprint() {
+		t.Errorf("expected fingerprint to use the name from the header")
+	}
+	// a released body needs the hash taken while decoding
+	hashed, _ := Decode(bytes.NewReader(raw), WithFingerprints())
+	hashed.Release()
+	if fp := hashed.Fingerprint(); fp != expected {
+		t.Errorf("expected fingerprint %s of a released part got %s", expected, fp)
+	}
+	part.Release()
+	if fp := part.Fingerprint(); fp != "" {
+		t.Errorf("expected no fingerprint without the data got %s", fp)
+	}
+	other.Number = 2
+	if other.Fingerprint() == expected {
+		t.Errorf("expected part number to change the fingerprint")
+	}
+}
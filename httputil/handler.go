@@ -0,0 +1,124 @@
+// Package httputil serves yenc decoding over HTTP
+package httputil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// Handler decodes yenc data POSTed to it and responds with a JSON
+// Response describing what was found. The body is decoded as it
+// arrives and the decoded data is not kept.
+type Handler struct {
+	// options passed to the decoder
+	Options []yenc.Option
+	// largest request body accepted, zero for no limit. Larger bodies
+	// get a 413 response.
+	MaxBytes int64
+	// logs failures to write a response, the log package's standard
+	// logger if nil
+	ErrorLog *log.Logger
+}
+
+// Response is the JSON written by Handler
+type Response struct {
+	// whether every part decoded and passed its checks
+	Valid bool `json:"valid"`
+	// why decoding failed
+	Error string `json:"error,omitempty"`
+	// number of decoded bytes and their sha256
+	Size   int64      `json:"size"`
+	SHA256 string     `json:"sha256"`
+	Parts  []PartInfo `json:"parts"`
+}
+
+// PartInfo describes one decoded part
+type PartInfo struct {
+	Name string `json:"name"`
+	// part number, zero for single part data
+	Number   int      `json:"part,omitempty"`
+	Begin    int64    `json:"begin,omitempty"`
+	End      int64    `json:"end,omitempty"`
+	Size     int64    `json:"size"`
+	CRC32    string   `json:"crc32"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "yenc data must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	body := io.Reader(r.Body)
+	if h.MaxBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.MaxBytes)
+	}
+	resp, err := h.decode(body)
+	status := http.StatusOK
+	if err != nil {
+		resp.Error = err.Error()
+		status = http.StatusUnprocessableEntity
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logf("httputil: writing response: %v", err)
+	}
+}
+
+func (h *Handler) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// decode the body, tracking each part as its lines go past
+func (h *Handler) decode(body io.Reader) (*Response, error) {
+	var parts []*yenc.Part
+	opts := append([]yenc.Option{
+		yenc.WithLineFunc(func(p *yenc.Part, line []byte) []byte {
+			if len(parts) == 0 || parts[len(parts)-1] != p {
+				parts = append(parts, p)
+			}
+			return line
+		}),
+	}, h.Options...)
+	sum := sha256.New()
+	n, err := io.Copy(sum, yenc.NewDecoder(body, opts...))
+	resp := &Response{
+		Valid:  err == nil,
+		Size:   n,
+		SHA256: hex.EncodeToString(sum.Sum(nil)),
+		Parts:  make([]PartInfo, len(parts)),
+	}
+	for i, p := range parts {
+		info := PartInfo{
+			Name:   p.Name,
+			Number: p.Number,
+			Begin:  p.Begin,
+			End:    p.End,
+			Size:   p.Size,
+			CRC32:  fmt.Sprintf("%08x", p.PayloadCRC32()),
+		}
+		for _, w := range p.Warnings {
+			info.Warnings = append(info.Warnings, w.Error())
+		}
+		resp.Parts[i] = info
+	}
+	return resp, err
+}
@@ -0,0 +1,65 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func post(t *testing.T, h http.Handler, body []byte) (*httptest.ResponseRecorder, *Response) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+	resp := new(Response)
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatalf("expected json response got %q", w.Body.String())
+	}
+	return w, resp
+}
+
+func TestHandler(t *testing.T) {
+	data, err := os.ReadFile("../multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	w, resp := post(t, new(Handler), data)
+	if w.Code != http.StatusOK || !resp.Valid {
+		t.Fatalf("expected valid response got %d %+v", w.Code, resp)
+	}
+	if resp.Size != 11250 || len(resp.Parts) != 1 || resp.Parts[0].CRC32 != "bfae5c0b" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestHandlerBroken(t *testing.T) {
+	data, err := os.ReadFile("../singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	data = bytes.Replace(data, []byte("size=584"), []byte("size=585"), -1)
+	w, resp := post(t, new(Handler), data)
+	if w.Code != http.StatusUnprocessableEntity || resp.Valid || resp.Error == "" {
+		t.Errorf("expected decode failure got %d %+v", w.Code, resp)
+	}
+}
+
+func TestHandlerMaxBytes(t *testing.T) {
+	data, err := os.ReadFile("../singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	w, resp := post(t, &Handler{MaxBytes: 100}, data)
+	if w.Code != http.StatusRequestEntityTooLarge || resp.Valid {
+		t.Errorf("expected 413 got %d %+v", w.Code, resp)
+	}
+}
+
+func TestHandlerMethod(t *testing.T) {
+	w := httptest.NewRecorder()
+	new(Handler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 got %d", w.Code)
+	}
+}
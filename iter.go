@@ -0,0 +1,43 @@
+package yenc
+
+import (
+	"io"
+	"iter"
+)
+
+// Parts returns an iterator over the parts in input, decoding each one
+// only when asked for so a long stream can be handled a part at a time.
+// A part that fails its checks is yielded along with the error and the
+// parts after it can still be read; any other error is yielded with a
+// nil part and ends the iteration. Checks of the file as a whole, such
+// as its crc32, run once the last part has been yielded and report a
+// failure as a final nil part. Bodies are not kept once yielded.
+func Parts(input io.Reader, opts ...Option) iter.Seq2[*Part, error] {
+	return func(yield func(*Part, error) bool) {
+		d := newDecoder(input, opts...)
+		defer d.release()
+		for {
+			d.forgetBodies()
+			p, err := d.next()
+			if err == io.EOF {
+				break
+			}
+			if !yield(p, err) || p == nil {
+				return
+			}
+		}
+		if err := d.finish(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// swap the parts already yielded for copies without their data, the
+// file checks only need their sizes
+func (d *decoder) forgetBodies() {
+	if n := len(d.parts); n > 0 && d.parts[n-1].Body != nil {
+		meta := *d.parts[n-1]
+		meta.Body, meta.Raw, meta.alloc = nil, nil, nil
+		d.parts[n-1] = &meta
+	}
+}
@@ -0,0 +1,50 @@
+package yenc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParts(t *testing.T) {
+	data := encodeTestData(5000)
+	var all bytes.Buffer
+	for _, a := range encodeParts(t, &MultipartEncoder{PartSize: 2000}, data) {
+		all.Write(a.Bytes())
+	}
+	var body []byte
+	for p, err := range Parts(&all) {
+		if err != nil {
+			t.Fatal("expected to decode: " + err.Error())
+		}
+		body = append(body, p.Body...)
+	}
+	if !bytes.Equal(body, data) {
+		t.Errorf("parts did not match input")
+	}
+}
+
+func TestPartsFailures(t *testing.T) {
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 2000}, encodeTestData(5000))
+	a := articles[0].String()
+	i := strings.Index(a, "pcrc32=") + len("pcrc32=")
+	input := a[:i] + "ffffffff" + a[i+8:] + articles[1].String() + articles[2].String()
+	var numbers []int
+	var errs int
+	for p, err := range Parts(strings.NewReader(input)) {
+		if err != nil {
+			errs++
+		}
+		if p != nil {
+			numbers = append(numbers, p.Number)
+		}
+	}
+	// the first part fails its pcrc32 and the rest still come through
+	if len(numbers) != 3 || errs != 1 {
+		t.Errorf("expected 3 parts and 1 error got %v and %d", numbers, errs)
+	}
+	for range Parts(strings.NewReader(input)) {
+		// stopping early must not panic
+		break
+	}
+}
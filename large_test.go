@@ -0,0 +1,134 @@
+package yenc
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+var large = flag.Bool("large", false, "stream more than 4GiB through the encoder and decoder")
+
+// offsets past 4GiB, where a 32 bit count would wrap
+const (
+	largeSize  int64 = 6 << 30
+	largeBegin int64 = 5<<30 + 1
+)
+
+// a part near the end of a 6GiB file, without the rest of the file
+func largePart() string {
+	return fmt.Sprintf("=ybegin part=9000 total=9000 line=128 size=%d name=big.bin\r\n"+
+		"=ypart begin=%d end=%d\r\n"+
+		"\x8b\x8c\x8d\r\n"+
+		"=yend size=3 part=9000 pcrc32=352441c2\r\n", largeSize, largeBegin, largeBegin+2)
+}
+
+func TestDecodeLargeOffsets(t *testing.T) {
+	res, err := DecodeResult(strings.NewReader(largePart()))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	p := res.Parts[0]
+	if p.Begin != largeBegin || p.End != largeBegin+2 || p.HeaderSize != largeSize {
+		t.Errorf("expected offsets to survive got begin %d end %d size %d", p.Begin, p.End, p.HeaderSize)
+	}
+	if err := QuickCheck(strings.NewReader(largePart())); err != nil {
+		t.Errorf("expected quick check to pass: %v", err)
+	}
+	report, err := Validate(strings.NewReader(largePart()))
+	if err != nil || !report.Valid() {
+		t.Errorf("expected to validate got %v (%v)", report.Issues, err)
+	}
+	// the assembler must wait for the rest of the file
+	s := new(memorySink)
+	a := NewSinkAssembler(s, 0)
+	if err := a.Add(p); err != nil || a.Written() != 0 || a.Done() {
+		t.Errorf("expected part to be held back got %d written (%v)", a.Written(), err)
+	}
+}
+
+func TestEncodeLargeOffsets(t *testing.T) {
+	var buf bytes.Buffer
+	e := newEncoder(&buf, "big.bin", largeSize)
+	e.part, e.total = 9000, 9000
+	e.begin, e.end = largeBegin, largeBegin+2
+	e.Write([]byte("abc"))
+	if err := e.Close(); err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	if buf.String() != largePart() {
+		t.Errorf("expected\n%q\ngot\n%q", largePart(), buf.String())
+	}
+	m := &MultipartEncoder{PartSize: 1 << 30}
+	sizes, err := m.plan(nil, "big.bin", largeSize)
+	if err != nil || len(sizes) != 6 {
+		t.Errorf("expected 6 parts of 1GiB got %v (%v)", sizes, err)
+	}
+}
+
+// counter is an endless reader of a repeating pattern, hashing what it hands out
+type counter struct {
+	n   byte
+	crc uint32
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = c.n
+		c.n += 7
+	}
+	c.crc = crc32.Update(c.crc, crc32.IEEETable, p)
+	return len(p), nil
+}
+
+func TestStreamLargeFile(t *testing.T) {
+	if !*large {
+		t.Skip("run with -large to stream 4GiB through the encoder and decoder")
+	}
+	const size int64 = 4<<30 + 17
+	src := new(counter)
+	pr, pw := io.Pipe()
+	go func() {
+		e := NewEncoder(pw, "big.bin", size)
+		if _, err := e.ReadFrom(io.LimitReader(src, size)); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(e.Close())
+	}()
+	crc := crc32.NewIEEE()
+	n, err := io.Copy(crc, NewDecoder(pr))
+	if err != nil {
+		t.Fatal("expected to stream: " + err.Error())
+	}
+	if n != size || crc.Sum32() != src.crc {
+		t.Errorf("expected %d bytes crc %08x got %d crc %08x", size, src.crc, n, crc.Sum32())
+	}
+}
+
+func TestDecodeHugeDeclaredSizes(t *testing.T) {
+	for _, size := range []string{"9223372036854775807", "-1", "4294967299"} {
+		input := "=ybegin line=128 size=" + size + " name=x\r\n\x8b\x8c\x8d\r\n=yend size=" + size + "\r\n"
+		for _, policy := range []SizePolicy{SizeError, SizeTruncate, SizePad} {
+			part, err := Decode(strings.NewReader(input), WithSizePolicy(policy))
+			if err == nil && len(part.Warnings) == 0 {
+				t.Errorf("size %s policy %d: expected size mismatch to be reported", size, policy)
+			}
+		}
+		report, err := Validate(strings.NewReader(input))
+		if err != nil || report.Valid() {
+			t.Errorf("size %s: expected validation issues (%v)", size, err)
+		}
+		if err := QuickCheck(strings.NewReader(input)); err == nil {
+			t.Errorf("size %s: expected quick check to fail", size)
+		}
+	}
+	// a line length that wraps to a small int on 32 bit platforms
+	input := "=ybegin line=4294967297 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3\r\n"
+	if report, _ := Validate(strings.NewReader(input)); !report.Valid() {
+		t.Errorf("expected huge line length to be accepted got %v", report.Issues)
+	}
+}
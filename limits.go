@@ -0,0 +1,119 @@
+package yenc
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// limits a decoder starts with, so hostile input can't make it hold
+// more than this much. The options below change them, zero or less
+// turning a limit off.
+const (
+	// bytes in a =ybegin, =ypart or =yend line
+	DefaultMaxHeaderLine = 16 << 10
+	// decoded bytes in a part held in memory, or declared for it by its
+	// headers. Parts streamed by NewDecoder, DecodeTo or DecodeInto
+	// hold no more than a line, so aren't limited without
+	// WithMaxPartSize.
+	DefaultMaxPartSize = 4 << 30
+	// parts read from one input
+	DefaultMaxParts = 100000
+)
+
+// LimitError is returned when input goes over one of the decoder's
+// limits, see WithMaxHeaderLine, WithMaxPartSize and WithMaxParts
+type LimitError struct {
+	// which limit, "header line", "part size" or "parts"
+	Limit string
+	// what the input has or claims, and the most allowed
+	Value, Max int64
+	// the part being read, if its header was
+	Part int
+	Name string
+}
+
+func (e *LimitError) Error() string {
+	switch {
+	case e.Name == "":
+		return fmt.Sprintf("yenc: %s of %d over the limit of %d", e.Limit, e.Value, e.Max)
+	case e.Part == 0:
+		return fmt.Sprintf("yenc: %s of %d in %s over the limit of %d", e.Limit, e.Value, e.Name, e.Max)
+	}
+	return fmt.Sprintf("yenc: %s of %d in part %d of %s over the limit of %d", e.Limit, e.Value, e.Part, e.Name, e.Max)
+}
+
+// WithMaxHeaderLine fails input with a =ybegin, =ypart or =yend line
+// longer than n bytes instead of reading it all into memory. Other long
+// lines before a header are skipped. It defaults to
+// DefaultMaxHeaderLine.
+func WithMaxHeaderLine(n int) Option {
+	return func(d *decoder) {
+		d.maxHeaderLine = n
+	}
+}
+
+// WithMaxParts fails input holding more than n parts, each of which
+// costs a Part kept until the decode ends. It defaults to
+// DefaultMaxParts.
+func WithMaxParts(n int) Option {
+	return func(d *decoder) {
+		d.maxParts = n
+	}
+}
+
+// error for going over a limit while reading the current part
+func (d *decoder) overLimit(limit string, value, max int64) error {
+	e := &LimitError{Limit: limit, Value: value, Max: max}
+	if d.part != nil {
+		e.Part, e.Name = d.part.Number, d.part.Name
+	}
+	return e
+}
+
+// error for a part longer than max allows
+func (d *decoder) tooLarge(n, max int64) error {
+	return d.overLimit("part size", n, max)
+}
+
+// the size limit for a part held in memory, zero for none
+func (d *decoder) bufferLimit() int64 {
+	if d.maxPartSize < 0 {
+		return DefaultMaxPartSize
+	}
+	return d.maxPartSize
+}
+
+// whether a line of n bytes is too long to be a header
+func (d *decoder) longHeader(n int) bool {
+	return d.maxHeaderLine > 0 && n > d.maxHeaderLine
+}
+
+// read a line that may be a header. Only the first maxHeaderLine bytes
+// of a longer line are returned, the rest is read past; n is the length
+// of the whole line.
+func (d *decoder) readHeaderLine() (s string, n int, err error) {
+	line, err := d.buf.ReadSlice('\n')
+	if err != bufio.ErrBufferFull && !d.longHeader(len(line)) {
+		return string(line), len(line), err
+	}
+	var b []byte
+	for {
+		n += len(line)
+		if room := d.maxHeaderLine - len(b); d.maxHeaderLine > 0 && len(line) > room {
+			line = line[:max(room, 0)]
+		}
+		b = append(b, line...)
+		if err != bufio.ErrBufferFull {
+			return string(b), n, err
+		}
+		line, err = d.buf.ReadSlice('\n')
+	}
+}
+
+// error for a header line of n bytes, if that is too long
+func (d *decoder) checkHeaderLine(n int) error {
+	if d.longHeader(n) {
+		return d.overLimit("header line", int64(n), int64(d.maxHeaderLine))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	var buf bytes.Buffer
+	Encode(&buf, "data.bin", encodeTestData(100))
+	good := buf.String()
+	long := strings.Repeat("x", DefaultMaxHeaderLine)
+	hostile := "=ybegin line=128 size=9999999999 name=x\r\nabc\r\n=yend size=3\r\n"
+	tests := []struct {
+		name, input string
+		opts        []Option
+		limit       string
+	}{
+		{"hostile size", hostile, []Option{WithMaxPartSize(1 << 20)}, "part size"},
+		{"long name", strings.Replace(good, "name=data.bin", "name="+long, 1), nil, "header line"},
+		{"long trailer", strings.Replace(good, "=yend ", "=yend junk="+long+" ", 1), nil, "header line"},
+		{"parts", good + good + good, []Option{WithMaxParts(2)}, "parts"},
+	}
+	for _, test := range tests {
+		_, err := DecodeAll(strings.NewReader(test.input), test.opts...)
+		var limit *LimitError
+		if !errors.As(err, &limit) || limit.Limit != test.limit {
+			t.Errorf("%s: expected a %s LimitError got %v", test.name, test.limit, err)
+		}
+		if _, err := io.Copy(io.Discard, NewDecoder(strings.NewReader(test.input), test.opts...)); !errors.As(err, &limit) {
+			t.Errorf("%s: expected the stream to fail with a LimitError got %v", test.name, err)
+		}
+	}
+	// by default only parts held in memory are limited
+	var limit *LimitError
+	if _, err := Decode(strings.NewReader(hostile)); !errors.As(err, &limit) || limit.Max != DefaultMaxPartSize {
+		t.Errorf("expected the default part size limit got %v", err)
+	} else if msg := "yenc: part size of 9999999999 in x over the limit of 4294967296"; err.Error() != msg {
+		t.Errorf("expected %q got %q", msg, err)
+	}
+	if _, err := io.Copy(io.Discard, NewDecoder(strings.NewReader(hostile))); errors.As(err, &limit) {
+		t.Errorf("expected a stream not to be limited by default got %v", err)
+	}
+	// long lines that aren't headers are skipped, and limits turned off
+	if _, err := Decode(strings.NewReader(long + "\r\n" + good)); err != nil {
+		t.Errorf("expected a long line before the header to be skipped got %v", err)
+	}
+	named := strings.Replace(good, "name=data.bin", "name="+long, 1)
+	if p, err := Decode(strings.NewReader(named), WithMaxHeaderLine(0)); err != nil || p.Name != long {
+		t.Errorf("expected no header line limit to decode got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package yenc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ExtractMaildir extracts the yenc and uuencoded files from every
+// message in the cur and new folders of a Maildir into e.Dir. Parts
+// are grouped into files by name across messages, so multipart sets
+// posted as separate messages are assembled. If progress is not nil it
+// is called after each message with the number done and the total.
+func (e *Extractor) ExtractMaildir(dir string, progress func(done, total int)) ([]*ExtractedFile, error) {
+	var paths []string
+	for _, sub := range []string{"cur", "new"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				paths = append(paths, filepath.Join(dir, sub, entry.Name()))
+			}
+		}
+	}
+	// maildir names start with the delivery time
+	sort.Slice(paths, func(i, j int) bool {
+		return filepath.Base(paths[i]) < filepath.Base(paths[j])
+	})
+	s := e.newFileSet()
+	for i, path := range paths {
+		if err := s.addMessageFile(path); err != nil {
+			return nil, fmt.Errorf("yenc: %s: %w", path, err)
+		}
+		if progress != nil {
+			progress(i+1, len(paths))
+		}
+	}
+	return s.files, e.writeFiles(s.files)
+}
+
+func (s *fileSet) addMessageFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.addMessage(f)
+}
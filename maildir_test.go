@@ -0,0 +1,44 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractMaildir(t *testing.T) {
+	data := encodeTestData(5000)
+	var parts []*bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		parts = append(parts, new(bytes.Buffer))
+		return parts[part-1], nil
+	})
+	maildir := t.TempDir()
+	for i, sub := range []string{"cur", "new", "tmp"} {
+		os.Mkdir(filepath.Join(maildir, sub), 0777)
+		msg := append([]byte("Subject: data.bin\r\n\r\n"), parts[i].Bytes()...)
+		name := fmt.Sprintf("100000000%d.M1P1.host", i)
+		if err := os.WriteFile(filepath.Join(maildir, sub, name), msg, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var calls []int
+	e := &Extractor{Dir: t.TempDir()}
+	files, err := e.ExtractMaildir(maildir, func(done, total int) {
+		calls = append(calls, done, total)
+	})
+	if err != nil {
+		t.Fatal("expected to extract maildir: " + err.Error())
+	}
+	// the part in tmp is still being delivered so the file is short
+	if len(files) != 1 || len(files[0].parts) != 2 {
+		t.Fatalf("expected one file of 2 parts got %+v", files)
+	}
+	if fmt.Sprint(calls) != "[1 2 2 2]" {
+		t.Errorf("unexpected progress calls %v", calls)
+	}
+}
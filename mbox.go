@@ -0,0 +1,77 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+)
+
+// MboxReader splits an mbox file into its messages
+type MboxReader struct {
+	buf *bufio.Reader
+	// set once the first From line has been seen
+	started bool
+	err     error
+}
+
+// NewMboxReader returns a reader for the messages in r, each of which
+// starts with a "From " line. Lines quoted as ">From " (mboxrd) have
+// one level of quoting removed.
+func NewMboxReader(r io.Reader) *MboxReader {
+	return &MboxReader{buf: bufio.NewReader(r)}
+}
+
+// Next returns the next message, or io.EOF when there are no more
+func (m *MboxReader) Next() (*mail.Message, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var msg bytes.Buffer
+	for {
+		s, err := m.buf.ReadString('\n')
+		if strings.HasPrefix(s, "From ") {
+			if m.started {
+				return mail.ReadMessage(&msg)
+			}
+			m.started = true
+			s = ""
+		}
+		if m.started {
+			if q := strings.TrimLeft(s, ">"); len(q) < len(s) && strings.HasPrefix(q, "From ") {
+				s = s[1:]
+			}
+			msg.WriteString(s)
+		}
+		if err != nil {
+			m.err = err
+			if msg.Len() > 0 {
+				return mail.ReadMessage(&msg)
+			}
+			return nil, err
+		}
+	}
+}
+
+// ExtractMbox extracts the yenc and uuencoded files from every message
+// in an mbox into e.Dir, assembling multipart sets whose parts were
+// posted as separate messages.
+func (e *Extractor) ExtractMbox(r io.Reader) ([]*ExtractedFile, error) {
+	s := e.newFileSet()
+	m := NewMboxReader(r)
+	for i := 1; ; i++ {
+		msg, err := m.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = s.addEntity(msg.Header, msg.Body)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("yenc: mbox message %d: %w", i, err)
+		}
+	}
+	return s.files, e.writeFiles(s.files)
+}
@@ -0,0 +1,67 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMboxReader(t *testing.T) {
+	mbox := "From a@example.com Mon Jan  1 00:00:00 2001\n" +
+		"Subject: one\n\nbody\n>From the start\n\n" +
+		"From b@example.com Mon Jan  1 00:00:00 2001\n" +
+		"Subject: two\n\nbody\n"
+	m := NewMboxReader(strings.NewReader(mbox))
+	var subjects, bodies []string
+	for {
+		msg, err := m.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("expected to read message: " + err.Error())
+		}
+		b, _ := io.ReadAll(msg.Body)
+		subjects = append(subjects, msg.Header.Get("Subject"))
+		bodies = append(bodies, string(b))
+	}
+	if strings.Join(subjects, ",") != "one,two" {
+		t.Errorf("expected subjects one,two got %v", subjects)
+	}
+	if len(bodies) != 2 || bodies[0] != "body\nFrom the start\n\n" {
+		t.Errorf("expected quoted From to be unquoted got %q", bodies)
+	}
+}
+
+func TestExtractMbox(t *testing.T) {
+	data := encodeTestData(5000)
+	var parts []*bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		parts = append(parts, new(bytes.Buffer))
+		return parts[part-1], nil
+	})
+	// one part per message, posted out of order
+	var mbox strings.Builder
+	for _, i := range []int{2, 0, 1} {
+		mbox.WriteString("From poster@example.com Mon Jan  1 00:00:00 2001\n")
+		mbox.WriteString("Subject: data.bin\n\n")
+		mbox.Write(parts[i].Bytes())
+		mbox.WriteString("\n")
+	}
+	e := &Extractor{Dir: t.TempDir()}
+	files, err := e.ExtractMbox(strings.NewReader(mbox.String()))
+	if err != nil {
+		t.Fatal("expected to extract mbox: " + err.Error())
+	}
+	if len(files) != 1 || !files[0].Valid {
+		t.Fatalf("expected one valid file got %+v", files)
+	}
+	b, err := os.ReadFile(filepath.Join(e.Dir, "data.bin"))
+	if err != nil || !bytes.Equal(b, data) {
+		t.Errorf("expected assembled file to match the original (%v)", err)
+	}
+}
@@ -0,0 +1,90 @@
+package yenc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// ExtractMessage parses an email or news article, such as a saved .eml
+// file, and extracts the yenc and uuencoded files found in its body or
+// in any of its MIME parts into e.Dir.
+func (e *Extractor) ExtractMessage(r io.Reader) ([]*ExtractedFile, error) {
+	s := e.newFileSet()
+	if err := s.addMessage(r); err != nil {
+		return nil, err
+	}
+	return s.files, e.writeFiles(s.files)
+}
+
+// parse a message and add the files in it to the set
+func (s *fileSet) addMessage(r io.Reader) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return err
+	}
+	return s.addEntity(msg.Header, msg.Body)
+}
+
+// mail.Header and textproto.MIMEHeader
+type header interface {
+	Get(key string) string
+}
+
+// add the files in a message body or MIME part, descending into
+// multipart bodies
+func (s *fileSet) addEntity(h header, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.addEntity(p.Header, p); err != nil {
+				return err
+			}
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return s.addBody(b)
+}
+
+// add the yenc and uuencoded files found in a text body
+func (s *fileSet) addBody(b []byte) error {
+	if bytes.Contains(b, []byte("=ybegin")) {
+		d := newDecoder(bytes.NewReader(b), s.opts...)
+		err := s.collect(d)
+		d.release()
+		if err != nil {
+			return err
+		}
+	}
+	parts, err := decodeUU(bytes.NewReader(b))
+	// uuencoded files go through the same filters
+	wanted := filterOf(s.opts)
+	for _, p := range parts {
+		if wanted(p) {
+			s.add(p, false, nil)
+		}
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package yenc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// a MIME message with a yenc body part and a uuencoded attachment
+func messageFixture(t *testing.T) string {
+	good := extractFixture(t)
+	return "From: poster@example.com\r\n" +
+		"Subject: joystick [1/1]\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"xx\"\r\n" +
+		"\r\n" +
+		"--xx\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		string(good) +
+		"\r\n--xx\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"begin 644 cat.txt\r\n#0V%T\r\n`\r\nend\r\n" +
+		"--xx--\r\n"
+}
+
+func TestExtractMessage(t *testing.T) {
+	e := &Extractor{Dir: t.TempDir()}
+	files, err := e.ExtractMessage(strings.NewReader(messageFixture(t)))
+	if err != nil {
+		t.Fatal("expected to extract message: " + err.Error())
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files got %d", len(files))
+	}
+	if files[0].Name != "testfile.txt" || !files[0].Valid || files[0].Size != 584 {
+		t.Errorf("unexpected yenc file %+v", files[0])
+	}
+	b, err := os.ReadFile(filepath.Join(e.Dir, "cat.txt"))
+	if err != nil || !bytes.Equal(b, []byte("Cat")) {
+		t.Errorf("expected uudecoded cat.txt to contain Cat got %q (%v)", b, err)
+	}
+}
+
+func TestDecodeUUMissingEnd(t *testing.T) {
+	if _, err := decodeUU(strings.NewReader("begin 644 cat.txt\n#0V%T\n")); err == nil {
+		t.Errorf("expected error for uuencoded data without end")
+	}
+}
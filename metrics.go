@@ -0,0 +1,80 @@
+package yenc
+
+import "time"
+
+// Metrics records where the time went during a decode, to tell a slow
+// network from a slow CPU
+type Metrics struct {
+	// time from the start of the decode to the last part
+	Wall time.Duration
+	// time spent waiting on the input, decoding and hashing body lines
+	Read, Decode, Hash time.Duration
+	// body bytes read and produced
+	EncodedBytes, DecodedBytes int64
+}
+
+// BytesPerSecond is the decoded throughput over the wall time
+func (m *Metrics) BytesPerSecond() float64 {
+	if m.Wall <= 0 {
+		return 0
+	}
+	return float64(m.DecodedBytes) / m.Wall.Seconds()
+}
+
+// stages that time is charged to
+const (
+	stageRead = iota
+	stageDecode
+	stageHash
+)
+
+// WithMetrics fills in m as the decode progresses
+func WithMetrics(m *Metrics) Option {
+	return func(d *decoder) {
+		d.metrics = m
+		d.started = time.Now()
+	}
+}
+
+// start timing a stage
+func (d *decoder) startLap() {
+	if d.metrics != nil {
+		d.mark = time.Now()
+	}
+}
+
+// charge the time since the last mark to a stage
+func (d *decoder) lap(stage int) {
+	if d.metrics == nil {
+		return
+	}
+	now := time.Now()
+	switch stage {
+	case stageRead:
+		d.metrics.Read += now.Sub(d.mark)
+	case stageDecode:
+		d.metrics.Decode += now.Sub(d.mark)
+	case stageHash:
+		d.metrics.Hash += now.Sub(d.mark)
+	}
+	d.mark = now
+	d.metrics.Wall = now.Sub(d.started)
+}
+
+// add the counts of n to m, leaving Wall alone
+func (m *Metrics) add(n *Metrics) {
+	m.Read += n.Read
+	m.Decode += n.Decode
+	m.Hash += n.Hash
+	m.EncodedBytes += n.EncodedBytes
+	m.DecodedBytes += n.DecodedBytes
+}
+
+// the Metrics set WithMetrics in opts, if any
+func metricsOf(opts []Option) *Metrics {
+	d := new(decoder)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d.metrics
+}
@@ -0,0 +1,29 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDecodeWithMetrics(t *testing.T) {
+	data := encodeTestData(100000)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "m.bin", int64(len(data)))
+	e.Write(data)
+	e.Close()
+	encoded := buf.Len()
+	var m Metrics
+	if _, err := io.Copy(io.Discard, NewDecoder(&buf, WithMetrics(&m))); err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if m.DecodedBytes != int64(len(data)) {
+		t.Errorf("expected %d decoded bytes got %d", len(data), m.DecodedBytes)
+	}
+	if m.EncodedBytes <= m.DecodedBytes || m.EncodedBytes >= int64(encoded) {
+		t.Errorf("unexpected encoded byte count %d", m.EncodedBytes)
+	}
+	if m.Wall <= 0 || m.Decode <= 0 || m.Read+m.Decode+m.Hash > m.Wall || m.BytesPerSecond() <= 0 {
+		t.Errorf("unexpected timings %+v", m)
+	}
+}
@@ -0,0 +1,196 @@
+package yenc
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// default payload bytes per part when splitting
+const DefaultPartSize = 700 << 10
+
+// MultipartEncoder splits a file into numbered yenc parts, one per
+// article
+type MultipartEncoder struct {
+	// payload bytes per part, DefaultPartSize if zero
+	PartSize int64
+	// if set, parts are sized so that each encoded article, headers
+	// and escapes included, is at most this many bytes. This takes
+	// precedence over PartSize.
+	MaxArticleSize int64
+	// encoded chars per line, DefaultLineLength if zero
+	LineLength int
+	// if set, give the lines written before =ybegin and after =yend of
+	// each part, such as article headers, see Encoder.Preamble. They
+	// count towards MaxArticleSize.
+	Preamble, Postamble func(part, total int) []string
+}
+
+func (m *MultipartEncoder) lineLength() int {
+	if m.LineLength > 0 {
+		return m.LineLength
+	}
+	return DefaultLineLength
+}
+
+// Encode splits the first size bytes of src into parts of a file called
+// name. next is called for the writer of each part in turn; if that
+// writer is also an io.Closer it is closed once the part is complete.
+func (m *MultipartEncoder) Encode(src io.ReaderAt, name string, size int64, next func(part, total int) (io.Writer, error)) error {
+	ends, err := m.plan(src, name, size)
+	if err != nil {
+		return err
+	}
+	return m.encode(io.NewSectionReader(src, 0, size), name, size, ends, next)
+}
+
+// EncodeReader is Encode for input that can only be read once, such as
+// a pipe. Parts are split by PartSize as the data is read, so
+// MaxArticleSize, which needs a pass over the data first, can't be
+// used.
+func (m *MultipartEncoder) EncodeReader(r io.Reader, name string, size int64, next func(part, total int) (io.Writer, error)) error {
+	if m.MaxArticleSize > 0 {
+		return fmt.Errorf("yenc: MaxArticleSize needs an io.ReaderAt, use Encode")
+	}
+	ends, err := m.plan(nil, name, size)
+	if err != nil {
+		return err
+	}
+	return m.encode(r, name, size, ends, next)
+}
+
+// encode the parts ending at ends, reading src in order
+func (m *MultipartEncoder) encode(src io.Reader, name string, size int64, ends []int64, next func(part, total int) (io.Writer, error)) error {
+	fileHash := crc32.NewIEEE()
+	var begin int64
+	for i, end := range ends {
+		w, err := next(i+1, len(ends))
+		if err != nil {
+			return err
+		}
+		e := newEncoder(w, name, size)
+		e.line = m.lineLength()
+		e.part, e.total = i+1, len(ends)
+		e.begin, e.end = begin+1, end
+		e.Preamble, e.Postamble = m.lines(i+1, len(ends))
+		r := io.TeeReader(io.LimitReader(src, end-begin), fileHash)
+		if _, err := e.ReadFrom(r); err != nil {
+			return err
+		}
+		if i == len(ends)-1 {
+			e.fileCRC, e.hasFileCRC = fileHash.Sum32(), true
+		}
+		if err := e.Close(); err != nil {
+			return err
+		}
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				return err
+			}
+		}
+		begin = end
+	}
+	return nil
+}
+
+// work out where each part ends
+func (m *MultipartEncoder) plan(src io.ReaderAt, name string, size int64) ([]int64, error) {
+	if m.MaxArticleSize > 0 {
+		return m.planArticles(src, name, size)
+	}
+	partSize := m.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	var ends []int64
+	for end := partSize; ; end += partSize {
+		if end >= size {
+			return append(ends, size), nil
+		}
+		ends = append(ends, end)
+	}
+}
+
+// the preamble and postamble for a part
+func (m *MultipartEncoder) lines(part, total int) (preamble, postamble []string) {
+	if m.Preamble != nil {
+		preamble = m.Preamble(part, total)
+	}
+	if m.Postamble != nil {
+		postamble = m.Postamble(part, total)
+	}
+	return preamble, postamble
+}
+
+// split so that every encoded article fits in MaxArticleSize. The
+// extra lines can depend on the number of parts, so the split is
+// redone with room for them until it settles.
+func (m *MultipartEncoder) planArticles(src io.ReaderAt, name string, size int64) ([]int64, error) {
+	var extra int64
+	for {
+		ends, err := m.splitArticles(src, name, size, extra)
+		if err != nil {
+			return nil, err
+		}
+		var need int64
+		for part := 1; part <= len(ends); part++ {
+			preamble, postamble := m.lines(part, len(ends))
+			need = max(need, linesLen(preamble)+linesLen(postamble))
+		}
+		if need <= extra {
+			return ends, nil
+		}
+		extra = need
+	}
+}
+
+// split by running the escaping rules over the data without producing
+// output, leaving extra bytes per article for other lines
+func (m *MultipartEncoder) splitArticles(src io.ReaderAt, name string, size, extra int64) ([]int64, error) {
+	line := m.lineLength()
+	overhead := articleOverhead(name, size, line) + extra
+	budget := m.MaxArticleSize - overhead
+	// a single escaped char plus line ending must fit
+	if budget < 4 {
+		return nil, fmt.Errorf("yenc: article size %d too small for headers of %d bytes", m.MaxArticleSize, overhead)
+	}
+	var ends []int64
+	var acc int64
+	col := 0
+	block := make([]byte, readFromBlockSize)
+	for off := int64(0); off < size; {
+		n, err := src.ReadAt(block[:min(int64(len(block)), size-off)], off)
+		if n == 0 && err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			c := block[i] + 42
+			w := int64(1)
+			esc := needsEscape(c, col, line)
+			if esc {
+				w = 2
+			}
+			newCol, newAcc := col+int(w), acc+w
+			lineEnd := newCol >= line
+			// what the part would cost if it ended after this char
+			final := newAcc + 2
+			if !lineEnd && !esc && (c == ' ' || c == '\t') {
+				final++
+			}
+			if final > budget && acc > 0 {
+				// start a new part with this char
+				ends = append(ends, off+int64(i))
+				acc, col = 0, 0
+				i--
+				continue
+			}
+			acc, col = newAcc, newCol
+			if lineEnd {
+				acc += 2
+				col = 0
+			}
+		}
+		off += int64(n)
+	}
+	return append(ends, size), nil
+}
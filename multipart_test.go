@@ -0,0 +1,148 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// encode data in parts returning each article
+func encodeParts(t *testing.T, m *MultipartEncoder, data []byte) []*bytes.Buffer {
+	var articles []*bytes.Buffer
+	err := m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		buf := new(bytes.Buffer)
+		articles = append(articles, buf)
+		return buf, nil
+	})
+	if err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	return articles
+}
+
+// decode the articles back into one file
+func decodeParts(t *testing.T, articles []*bytes.Buffer) []byte {
+	var all bytes.Buffer
+	for _, a := range articles {
+		all.Write(a.Bytes())
+	}
+	if _, err := Decode(bytes.NewReader(all.Bytes())); err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	e := &Extractor{Dir: t.TempDir()}
+	files, err := e.Extract(&all)
+	if err != nil || len(files) != 1 || !files[0].Valid {
+		t.Fatalf("expected one valid file got %v (%v)", files, err)
+	}
+	out, err := os.ReadFile(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMultipartEncodePartSize(t *testing.T) {
+	data := encodeTestData(10000)
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 3000}, data)
+	if len(articles) != 4 {
+		t.Fatalf("expected 4 parts got %d", len(articles))
+	}
+	if !bytes.Equal(decodeParts(t, articles), data) {
+		t.Errorf("decoded parts did not match input")
+	}
+}
+
+func TestMultipartEncodeReader(t *testing.T) {
+	data := encodeTestData(10000)
+	var articles []*bytes.Buffer
+	m := &MultipartEncoder{PartSize: 3000}
+	// only io.Reader is available
+	src := io.MultiReader(bytes.NewReader(data))
+	err := m.EncodeReader(src, "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		buf := new(bytes.Buffer)
+		articles = append(articles, buf)
+		return buf, nil
+	})
+	if err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	if len(articles) != 4 || !bytes.Equal(decodeParts(t, articles), data) {
+		t.Errorf("expected 4 parts matching the input got %d", len(articles))
+	}
+	short := io.MultiReader(bytes.NewReader(data[:5000]))
+	if err := m.EncodeReader(short, "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return io.Discard, nil
+	}); err == nil {
+		t.Error("expected an error for input shorter than size")
+	}
+	m.MaxArticleSize = 4000
+	if err := m.EncodeReader(bytes.NewReader(data), "data.bin", int64(len(data)), nil); err == nil {
+		t.Error("expected an error for MaxArticleSize without io.ReaderAt")
+	}
+}
+
+func TestMultipartEncodeMaxArticleSize(t *testing.T) {
+	data := encodeTestData(50000)
+	// a run of chars that all need escaping
+	for i := 10000; i < 20000; i++ {
+		data[i] = '=' - 42
+	}
+	articles := encodeParts(t, &MultipartEncoder{MaxArticleSize: 4000}, data)
+	for i, a := range articles {
+		if a.Len() > 4000 {
+			t.Errorf("article %d is %d bytes", i+1, a.Len())
+		}
+	}
+	if !bytes.Equal(decodeParts(t, articles), data) {
+		t.Errorf("decoded parts did not match input")
+	}
+}
+
+func TestMultipartEncodePreamble(t *testing.T) {
+	data := encodeTestData(50000)
+	m := &MultipartEncoder{
+		MaxArticleSize: 4000,
+		Preamble: func(part, total int) []string {
+			return []string{fmt.Sprintf("Subject: data.bin (%d/%d)", part, total), ""}
+		},
+		Postamble: func(part, total int) []string {
+			return []string{"-- ", "posted by a test"}
+		},
+	}
+	articles := encodeParts(t, m, data)
+	for i, a := range articles {
+		if a.Len() > 4000 {
+			t.Errorf("article %d is %d bytes", i+1, a.Len())
+		}
+		subject := fmt.Sprintf("Subject: data.bin (%d/%d)\r\n\r\n=ybegin", i+1, len(articles))
+		if !bytes.HasPrefix(a.Bytes(), []byte(subject)) || !bytes.HasSuffix(a.Bytes(), []byte("\r\n-- \r\nposted by a test\r\n")) {
+			t.Errorf("article %d missing preamble or postamble", i+1)
+		}
+	}
+	if !bytes.Equal(decodeParts(t, articles), data) {
+		t.Errorf("decoded parts did not match input")
+	}
+}
+
+func TestDecodeInconsistentSize(t *testing.T) {
+	data := encodeTestData(5000)
+	var all bytes.Buffer
+	for _, a := range encodeParts(t, &MultipartEncoder{PartSize: 2000}, data) {
+		all.Write(a.Bytes())
+	}
+	// claim the file is bigger than the parts
+	bad := bytes.Replace(all.Bytes(), []byte("size=5000 "), []byte("size=6000 "), -1)
+	if _, err := Decode(bytes.NewReader(bad)); !errors.Is(err, ErrInconsistentSize) {
+		t.Errorf("expected ErrInconsistentSize got %v", err)
+	}
+	part, err := Decode(bytes.NewReader(bad), WithSizePolicy(SizeAccept))
+	if err != nil {
+		t.Fatal("expected to decode with size accept: " + err.Error())
+	}
+	if part.Number != 1 {
+		t.Errorf("expected first part got %d", part.Number)
+	}
+}
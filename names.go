@@ -0,0 +1,152 @@
+package yenc
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Charset is the encoding of the names in yenc headers, which the spec
+// leaves open. Names from old posts are often in a DOS or Latin-1 code
+// page rather than UTF-8.
+type Charset int
+
+const (
+	// names are kept as given (the default)
+	CharsetUTF8 Charset = iota
+	// ISO-8859-1
+	CharsetLatin1
+	// the DOS code page 437
+	CharsetCP437
+	// names that are valid UTF-8 are kept, others are taken as CP437
+	// if they use the bytes 0x80-0x9f, which Latin-1 leaves to control
+	// chars and CP437 gives accented letters, or else as Latin-1
+	CharsetAuto
+)
+
+// chars 0x80-0xff of code page 437
+var cp437High = []rune("ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0")
+
+// DecodeName converts a name from a yenc header in charset c to UTF-8
+func DecodeName(name string, c Charset) string {
+	if c == CharsetAuto {
+		switch {
+		case utf8.ValidString(name):
+			return name
+		case hasC1(name):
+			c = CharsetCP437
+		default:
+			c = CharsetLatin1
+		}
+	}
+	if c != CharsetLatin1 && c != CharsetCP437 {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch ch := name[i]; {
+		case ch < 0x80:
+			b.WriteByte(ch)
+		case c == CharsetCP437:
+			b.WriteRune(cp437High[ch-0x80])
+		default:
+			b.WriteRune(rune(ch))
+		}
+	}
+	return b.String()
+}
+
+// whether s has bytes Latin-1 uses for C1 control chars
+func hasC1(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 && s[i] < 0xa0 {
+			return true
+		}
+	}
+	return false
+}
+
+// longest name SanitizeName returns, in bytes, which most filesystems
+// allow
+const maxNameLen = 255
+
+// SanitizeName makes a name from a yenc header safe to create as a file
+// in a directory of the caller's choosing. Path elements are joined with
+// underscores, dropping empty, "." and ".." ones, so a name can't climb
+// out of the directory. Control chars are removed, chars Windows won't
+// have in a name become underscores and names over 255 bytes are cut
+// short, keeping their extension. A name with nothing left is
+// "unnamed".
+func SanitizeName(name string) string {
+	var elems []string
+	for _, elem := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		elem = strings.Map(func(r rune) rune {
+			switch {
+			case r < ' ' || r == 0x7f:
+				return -1
+			case strings.ContainsRune(`<>:"|?*`, r):
+				return '_'
+			}
+			return r
+		}, elem)
+		elem = strings.TrimSpace(elem)
+		if elem != "" && elem != "." && elem != ".." {
+			elems = append(elems, elem)
+		}
+	}
+	name = strings.Join(elems, "_")
+	if len(name) > maxNameLen {
+		ext := name[strings.LastIndexByte(name, '.')+1:]
+		if len(ext) > 16 || len(ext) == len(name) {
+			ext = ""
+		} else {
+			ext = "." + ext
+		}
+		name = truncateUTF8(name[:len(name)-len(ext)], maxNameLen-len(ext)) + ext
+	}
+	if name == "" {
+		return "unnamed"
+	}
+	return name
+}
+
+// cut s to at most n bytes without splitting a char
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// WithSanitizedNames sets Part.Name to the header's name passed through
+// SanitizeName, so it can be used as a file name without letting a post
+// named "../../etc/cron.d/x" write outside a download directory. The
+// name as given stays in Part.RawName.
+func WithSanitizedNames() Option {
+	return func(d *decoder) {
+		d.sanitizeNames = true
+	}
+}
+
+// WithNameCharset converts the names in headers from c to UTF-8, so
+// they display properly and can go into JSON. The name as given stays
+// in Part.RawName.
+func WithNameCharset(c Charset) Option {
+	return func(d *decoder) {
+		d.nameCharset = c
+	}
+}
+
+// set the part's name from its header
+func (d *decoder) setName(name string) {
+	d.part.RawName = name
+	name = DecodeName(name, d.nameCharset)
+	if d.sanitizeNames {
+		name = SanitizeName(name)
+	}
+	d.part.Name = name
+}
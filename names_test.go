@@ -0,0 +1,73 @@
+package yenc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"file.bin":                 "file.bin",
+		"../../etc/cron.d/x":       "etc_cron.d_x",
+		`..\..\windows\system.ini`: "windows_system.ini",
+		"/abs/path.txt":            "abs_path.txt",
+		"bad\x00\x1bname\x7f.txt":  "badname.txt",
+		`what?<is>"this"|*.txt`:    "what__is__this___.txt",
+		" .. ":                     "unnamed",
+		"":                         "unnamed",
+	}
+	for name, want := range tests {
+		if got := SanitizeName(name); got != want {
+			t.Errorf("SanitizeName(%q) = %q expected %q", name, got, want)
+		}
+	}
+	long := SanitizeName(strings.Repeat("é", 200) + ".mkv")
+	if len(long) > maxNameLen || !strings.HasSuffix(long, "é.mkv") {
+		t.Errorf("expected a long name cut to %d bytes keeping its extension got %d bytes %q", maxNameLen, len(long), long)
+	}
+}
+
+func TestDecodeWithSanitizedNames(t *testing.T) {
+	input := "=ybegin line=128 size=3 name=../../etc/cron.d/x\r\nKLM\r\n=yend size=3\r\n"
+	p, err := Decode(strings.NewReader(input), WithSanitizedNames())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if p.Name != "etc_cron.d_x" || p.RawName != "../../etc/cron.d/x" {
+		t.Errorf("unexpected names %q and %q", p.Name, p.RawName)
+	}
+	if p, _ := Decode(strings.NewReader(input)); p.Name != p.RawName {
+		t.Errorf("expected names to be left alone by default got %q", p.Name)
+	}
+}
+
+func TestDecodeName(t *testing.T) {
+	if len(cp437High) != 128 {
+		t.Fatalf("expected 128 chars in the CP437 table got %d", len(cp437High))
+	}
+	tests := []struct {
+		name    string
+		charset Charset
+		want    string
+	}{
+		{"caf\xe9.txt", CharsetLatin1, "café.txt"},
+		{"caf\x82.txt", CharsetCP437, "café.txt"},
+		{"caf\xe9.txt", CharsetAuto, "café.txt"},
+		{"caf\x82.txt", CharsetAuto, "café.txt"},
+		{"café.txt", CharsetAuto, "café.txt"},
+		{"caf\xe9.txt", CharsetUTF8, "caf\xe9.txt"},
+	}
+	for _, test := range tests {
+		if got := DecodeName(test.name, test.charset); got != test.want {
+			t.Errorf("DecodeName(%q, %d) = %q expected %q", test.name, test.charset, got, test.want)
+		}
+	}
+	input := "=ybegin line=128 size=3 name=\x9arger.bin\r\nKLM\r\n=yend size=3\r\n"
+	p, err := Decode(strings.NewReader(input), WithNameCharset(CharsetAuto))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if p.Name != "Ürger.bin" || p.RawName != "\x9arger.bin" {
+		t.Errorf("unexpected names %q and %q", p.Name, p.RawName)
+	}
+}
@@ -0,0 +1,104 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// articleReader undoes the NNTP framing of an article, see
+// NewArticleReader
+type articleReader struct {
+	buf *bufio.Reader
+	// whether the article headers have been dealt with, and whether
+	// the next read starts a line
+	begun, lineStart bool
+	// body bytes not yet read
+	pending []byte
+	err     error
+}
+
+// NewArticleReader returns a reader over the body of an article as
+// sent by an NNTP server in reply to ARTICLE or BODY. Headers, if the
+// article starts with them, are skipped up to the blank line after
+// them, a doubled dot at the start of a line is unstuffed and the
+// lone dot ending the article ends the stream. See also WithNNTP.
+func NewArticleReader(r io.Reader) io.Reader {
+	return &articleReader{buf: bufio.NewReader(r), lineStart: true}
+}
+
+func (a *articleReader) Read(p []byte) (int, error) {
+	for len(a.pending) == 0 {
+		if a.err != nil {
+			return 0, a.err
+		}
+		a.pending, a.err = a.next()
+	}
+	n := copy(p, a.pending)
+	a.pending = a.pending[n:]
+	return n, nil
+}
+
+// the next piece of the body, a line or part of a long one
+func (a *articleReader) next() ([]byte, error) {
+	if !a.begun {
+		a.begun = true
+		if err := a.skipHeaders(); err != nil {
+			return nil, err
+		}
+	}
+	line, err := a.buf.ReadSlice('\n')
+	start := a.lineStart
+	a.lineStart = err == nil
+	if err == bufio.ErrBufferFull {
+		err = nil
+	}
+	if start && len(line) > 0 && line[0] == '.' {
+		if len(bytes.TrimRight(line, "\r\n")) == 1 {
+			return nil, io.EOF
+		}
+		line = line[1:]
+	}
+	return line, err
+}
+
+// skip the headers if the article starts with them
+func (a *articleReader) skipHeaders() error {
+	if !isHeaderStart(a.buf) {
+		return nil
+	}
+	for start := true; ; {
+		line, err := a.buf.ReadSlice('\n')
+		if start && len(bytes.TrimRight(line, "\r\n")) == 0 && err == nil {
+			return nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return err
+		}
+		start = err == nil
+	}
+}
+
+// whether the buffered input starts with a header line: a name of
+// printable chars other than space and colon, then a colon
+func isHeaderStart(buf *bufio.Reader) bool {
+	// the name of the longest standard header fits easily
+	b, _ := buf.Peek(80)
+	for i, c := range b {
+		switch {
+		case c == ':':
+			return i > 0
+		case c <= ' ' || c > '~':
+			return false
+		}
+	}
+	return false
+}
+
+// WithNNTP reads the input as an article sent by an NNTP server, see
+// NewArticleReader, so stuffed dots are undone before decoding
+func WithNNTP() Option {
+	return func(d *decoder) {
+		d.nntp = true
+	}
+}
@@ -0,0 +1,43 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// an article as a server sends it, dots at line starts stuffed rather
+// than escaped
+func stuffedArticle(t *testing.T, headers string, data []byte) string {
+	var buf bytes.Buffer
+	if err := Encode(&buf, "dots.bin", data); err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	body := strings.ReplaceAll(buf.String(), "\r\n=n", "\r\n..")
+	return headers + body + ".\r\nnot part of the article\r\n"
+}
+
+func TestArticleReader(t *testing.T) {
+	data := bytes.Repeat([]byte{'.' - 42}, 1000)
+	for _, headers := range []string{"From: poster\r\nSubject: dots\r\n\r\n", ""} {
+		article := stuffedArticle(t, headers, data)
+		if !strings.Contains(article, "\r\n..") {
+			t.Fatal("expected stuffed dots in the article")
+		}
+		part, err := Decode(strings.NewReader(article), WithNNTP())
+		if err != nil {
+			t.Fatal("expected to decode: " + err.Error())
+		}
+		if !bytes.Equal(part.Body, data) {
+			t.Errorf("decoded %d bytes did not match", len(part.Body))
+		}
+		body, _ := io.ReadAll(NewArticleReader(strings.NewReader(article)))
+		if bytes.Contains(body, []byte("not part")) || bytes.Contains(body, []byte("Subject")) {
+			t.Errorf("expected only the body got %q", body[len(body)-40:])
+		}
+	}
+	if _, err := Decode(strings.NewReader(stuffedArticle(t, "", data))); err == nil {
+		t.Error("expected stuffed dots to break decoding without WithNNTP")
+	}
+}
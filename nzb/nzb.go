@@ -0,0 +1,134 @@
+// Package nzb reads NZB files, the XML indexes listing the articles
+// that make up a Usenet post, and checks decoded files against them
+package nzb
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// NZB is a parsed NZB document
+type NZB struct {
+	// values from <meta type="..."> in <head>, eg "title" or "password"
+	Meta  map[string][]string
+	Files []*File
+}
+
+// File is one posted file
+type File struct {
+	Poster string
+	// unix time of posting
+	Date    int64
+	Subject string
+	Groups  []string
+	// in the order given in the document
+	Segments []Segment
+}
+
+// Segment is one article of a file
+type Segment struct {
+	// part number, from 1
+	Number int
+	// size of the article as posted. This covers the encoded data and
+	// its headers, so is a little larger than the decoded part.
+	Bytes     int64
+	MessageID string
+}
+
+// the document as it is laid out in XML
+type document struct {
+	XMLName xml.Name  `xml:"nzb"`
+	Xmlns   string    `xml:"xmlns,attr,omitempty"`
+	Meta    []docMeta `xml:"head>meta"`
+	Files   []docFile `xml:"file"`
+}
+
+type docMeta struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type docFile struct {
+	Poster   string       `xml:"poster,attr"`
+	Date     int64        `xml:"date,attr"`
+	Subject  string       `xml:"subject,attr"`
+	Groups   []string     `xml:"groups>group"`
+	Segments []docSegment `xml:"segments>segment"`
+}
+
+type docSegment struct {
+	Bytes     int64  `xml:"bytes,attr"`
+	Number    int    `xml:"number,attr"`
+	MessageID string `xml:",chardata"`
+}
+
+// Parse reads an NZB document. Documents declared as ISO-8859-1, as
+// many are, are converted to UTF-8.
+func Parse(r io.Reader) (*NZB, error) {
+	var doc document
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("nzb: %w", err)
+	}
+	n := &NZB{Meta: make(map[string][]string)}
+	for _, m := range doc.Meta {
+		n.Meta[m.Type] = append(n.Meta[m.Type], strings.TrimSpace(m.Value))
+	}
+	for _, df := range doc.Files {
+		f := &File{Poster: df.Poster, Date: df.Date, Subject: df.Subject}
+		for _, g := range df.Groups {
+			f.Groups = append(f.Groups, strings.TrimSpace(g))
+		}
+		for _, s := range df.Segments {
+			f.Segments = append(f.Segments, Segment{
+				Number:    s.Number,
+				Bytes:     s.Bytes,
+				MessageID: strings.Trim(strings.TrimSpace(s.MessageID), "<>"),
+			})
+		}
+		n.Files = append(n.Files, f)
+	}
+	return n, nil
+}
+
+// decode the single byte charsets NZBs are found in
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1", "us-ascii", "ascii":
+		b, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, 0, len(b))
+		for _, c := range b {
+			out = utf8.AppendRune(out, rune(c))
+		}
+		return bytes.NewReader(out), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// Name returns the file name from the subject, the quoted part of
+// subjects like `"file.rar" yEnc (1/20)`, or the whole subject if
+// nothing is quoted. Quotes in the name are kept, it runs to the last
+// quote.
+func (f *File) Name() string {
+	if i, j := strings.IndexByte(f.Subject, '"'), strings.LastIndexByte(f.Subject, '"'); j > i+1 {
+		return f.Subject[i+1 : j]
+	}
+	return f.Subject
+}
+
+// Bytes returns the total size of the file's segments
+func (f *File) Bytes() int64 {
+	var n int64
+	for _, s := range f.Segments {
+		n += s.Bytes
+	}
+	return n
+}
@@ -0,0 +1,142 @@
+package nzb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/chrisfarms/yenc"
+)
+
+const sample = `<?xml version="1.0" encoding="iso-8859-1" ?>
+<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.1//EN" "http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd">
+<nzb xmlns="http://www.newzbin.com/DTD/2003/nzb">
+ <head>
+  <meta type="title">Holiday</meta>
+ </head>
+ <file poster="Ren` + "\xe9" + ` &lt;rene@example.com&gt;" date="1071674882" subject="Holiday [1/1] - &quot;photos.rar&quot; yEnc (1/2)">
+  <groups>
+   <group>alt.binaries.test</group>
+  </groups>
+  <segments>
+   <segment bytes="102394" number="1">part1of2@example.com</segment>
+   <segment bytes="4501" number="2">part2of2@example.com</segment>
+  </segments>
+ </file>
+</nzb>
+`
+
+func TestParse(t *testing.T) {
+	n, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal("expected to parse: " + err.Error())
+	}
+	if n.Meta["title"][0] != "Holiday" || len(n.Files) != 1 {
+		t.Fatalf("unexpected document %+v", n)
+	}
+	f := n.Files[0]
+	if f.Poster != "René <rene@example.com>" || f.Date != 1071674882 || f.Groups[0] != "alt.binaries.test" {
+		t.Errorf("unexpected file %+v", f)
+	}
+	if f.Name() != "photos.rar" || f.Bytes() != 106895 {
+		t.Errorf("unexpected name %q or bytes %d", f.Name(), f.Bytes())
+	}
+	if s := f.Segments[1]; s.Number != 2 || s.Bytes != 4501 || s.MessageID != "part2of2@example.com" {
+		t.Errorf("unexpected segment %+v", s)
+	}
+}
+
+// encode a file in parts of 50000 bytes and extract it, returning the
+// extracted file and the size of each article
+func extracted(t *testing.T) (*yenc.ExtractedFile, []int64) {
+	data := make([]byte, 120000)
+	rand.New(rand.NewSource(1)).Read(data)
+	var encoded bytes.Buffer
+	var sizes []int64
+	m := &yenc.MultipartEncoder{PartSize: 50000}
+	err := m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		if part > 1 {
+			sizes = append(sizes, int64(encoded.Len()))
+		}
+		return &encoded, nil
+	})
+	if err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	sizes = append(sizes, int64(encoded.Len()))
+	// each article is its own size, plus some headers
+	for i := len(sizes) - 1; i > 0; i-- {
+		sizes[i] -= sizes[i-1]
+	}
+	for i := range sizes {
+		sizes[i] += 500
+	}
+	files, err := (&yenc.Extractor{Dir: t.TempDir()}).Extract(&encoded)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected to extract one file: %v", err)
+	}
+	return files[0], sizes
+}
+
+func listing(name string, sizes []int64) *NZB {
+	f := &File{Subject: fmt.Sprintf("%q yEnc (1/%d)", name, len(sizes))}
+	for i, size := range sizes {
+		f.Segments = append(f.Segments, Segment{Number: i + 1, Bytes: size})
+	}
+	return &NZB{Files: []*File{f}}
+}
+
+func TestVerify(t *testing.T) {
+	file, sizes := extracted(t)
+	if found := listing("data.bin", sizes).Verify([]*yenc.ExtractedFile{file}, 0); len(found) > 0 {
+		t.Errorf("expected no discrepancies got %v", found)
+	}
+
+	// a segment listed as much larger than what was decoded
+	grown := append([]int64(nil), sizes...)
+	grown[1] *= 2
+	found := listing("data.bin", grown).Verify([]*yenc.ExtractedFile{file}, 0)
+	if len(found) != 1 || found[0].Segment != 2 || !strings.Contains(found[0].Msg, "truncated") {
+		t.Errorf("expected segment 2 to look truncated got %v", found)
+	}
+
+	// a segment missing from the NZB, and a file missing from the decode
+	n := listing("data.bin", sizes[:2])
+	n.Files = append(n.Files, listing("other.bin", sizes).Files...)
+	found = n.Verify([]*yenc.ExtractedFile{file}, 0)
+	if len(found) != 3 || found[1].Segment != 3 || found[2].File != "other.bin" {
+		t.Errorf("unexpected discrepancies %v", found)
+	}
+
+	// sizes hold once the bodies are released
+	for _, p := range file.Parts() {
+		p.Release()
+	}
+	if found := listing("data.bin", sizes).Verify([]*yenc.ExtractedFile{file}, 0); len(found) > 0 {
+		t.Errorf("expected no discrepancies for released parts got %v", found)
+	}
+}
+
+func TestMatchSegment(t *testing.T) {
+	file, sizes := extracted(t)
+	n := listing("data.bin", sizes)
+	for _, p := range file.Parts() {
+		f, s := n.Match(p)
+		if f == nil || s == nil || s.Number != p.Number {
+			t.Fatalf("expected part %d to match its segment", p.Number)
+		}
+		if err := s.Verify(p, 0); err != nil {
+			t.Errorf("expected part %d to fit: %v", p.Number, err)
+		}
+	}
+	p := file.Parts()[0]
+	if err := n.Files[0].Segment(2).Verify(p, 0); err == nil {
+		t.Error("expected the wrong segment to fail")
+	}
+	if f, s := listing("other.bin", sizes).Match(p); f != nil || s != nil {
+		t.Error("expected no match for another file")
+	}
+}
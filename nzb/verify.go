@@ -0,0 +1,163 @@
+package nzb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// DefaultTolerance is how much smaller than its article a decoded part
+// may be, as a fraction of the segment's bytes, before Verify reports
+// it. yenc adds a few percent of escapes and line endings.
+const DefaultTolerance = 0.1
+
+// room for article and yenc headers, which dominate small segments
+const headerSlack = 2048
+
+// Discrepancy is a way a decoded file disagrees with the NZB
+type Discrepancy struct {
+	// name of the file
+	File string
+	// segment number, zero if it is about the whole file
+	Segment int
+	Msg     string
+}
+
+func (d Discrepancy) String() string {
+	if d.Segment > 0 {
+		return fmt.Sprintf("%s segment %d: %s", d.File, d.Segment, d.Msg)
+	}
+	return fmt.Sprintf("%s: %s", d.File, d.Msg)
+}
+
+// Verify checks decoded files against the NZB entries of the same
+// name, taking files of the same name as pieces of one, as when each
+// article was extracted on its own. Besides files and segments missing
+// on either side it catches
+// parts whose size doesn't fit their article: larger than it, or
+// smaller by more than tolerance, as a segment truncated before it was
+// encoded would be while still passing its crc. A tolerance of zero
+// means DefaultTolerance.
+func (n *NZB) Verify(files []*yenc.ExtractedFile, tolerance float64) []Discrepancy {
+	var found []Discrepancy
+	listed := make(map[string]*File)
+	for _, f := range n.Files {
+		listed[f.Name()] = f
+	}
+	var names []string
+	parts := make(map[string][]*yenc.Part)
+	for _, ef := range files {
+		if _, ok := parts[ef.Name]; !ok {
+			names = append(names, ef.Name)
+		}
+		parts[ef.Name] = append(parts[ef.Name], ef.Parts()...)
+	}
+	for _, name := range names {
+		f, ok := listed[name]
+		if !ok {
+			found = append(found, Discrepancy{File: name, Msg: "not listed in the NZB"})
+			continue
+		}
+		found = append(found, f.Verify(parts[name], tolerance)...)
+	}
+	for _, f := range n.Files {
+		if _, ok := parts[f.Name()]; !ok {
+			found = append(found, Discrepancy{File: f.Name(), Msg: "listed in the NZB but not decoded"})
+		}
+	}
+	return found
+}
+
+// Verify checks the decoded parts of one file against its segments,
+// see NZB.Verify
+func (f *File) Verify(parts []*yenc.Part, tolerance float64) []Discrepancy {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	name := f.Name()
+	var found []Discrepancy
+	report := func(segment int, format string, args ...interface{}) {
+		found = append(found, Discrepancy{File: name, Segment: segment, Msg: fmt.Sprintf(format, args...)})
+	}
+	if len(parts) != len(f.Segments) {
+		report(0, "%d parts decoded but the NZB lists %d segments", len(parts), len(f.Segments))
+	}
+	byNumber := make(map[int]*yenc.Part)
+	for _, p := range parts {
+		// a single part file is posted as segment 1
+		byNumber[max(p.Number, 1)] = p
+	}
+	segments := make(map[int]bool)
+	for _, s := range f.Segments {
+		segments[s.Number] = true
+		p, ok := byNumber[s.Number]
+		if !ok {
+			report(s.Number, "not decoded")
+			continue
+		}
+		if msg := s.check(p, tolerance); msg != "" {
+			report(s.Number, "%s", msg)
+		}
+	}
+	var extra []int
+	for number := range byNumber {
+		if !segments[number] {
+			extra = append(extra, number)
+		}
+	}
+	sort.Ints(extra)
+	for _, number := range extra {
+		report(number, "decoded but not listed in the NZB")
+	}
+	return found
+}
+
+// Match finds the file and segment of the NZB that p was posted as,
+// going by name and part number. Either is nil if not listed.
+func (n *NZB) Match(p *yenc.Part) (*File, *Segment) {
+	for _, f := range n.Files {
+		if f.Name() == p.Name {
+			return f, f.Segment(max(p.Number, 1))
+		}
+	}
+	return nil, nil
+}
+
+// Segment returns the segment with the given number, or nil
+func (f *File) Segment(number int) *Segment {
+	for i := range f.Segments {
+		if f.Segments[i].Number == number {
+			return &f.Segments[i]
+		}
+	}
+	return nil
+}
+
+// Verify checks a decoded part against the segment it was posted as:
+// the part number must match and its size must fit the article, see
+// NZB.Verify. A tolerance of zero means DefaultTolerance.
+func (s *Segment) Verify(p *yenc.Part, tolerance float64) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if number := max(p.Number, 1); number != s.Number {
+		return fmt.Errorf("nzb: part %d decoded from segment %d", number, s.Number)
+	}
+	if msg := s.check(p, tolerance); msg != "" {
+		return fmt.Errorf("nzb: segment %d: %s", s.Number, msg)
+	}
+	return nil
+}
+
+// what is wrong with the size of p for this segment, if anything
+func (s *Segment) check(p *yenc.Part, tolerance float64) string {
+	size := p.DecodedLen()
+	switch {
+	case size > s.Bytes:
+		return fmt.Sprintf("decoded %d bytes, more than the %d byte article", size, s.Bytes)
+	case float64(size) < (1-tolerance)*float64(s.Bytes)-headerSlack:
+		return fmt.Sprintf("decoded only %d bytes from a %d byte article, it may have been truncated", size, s.Bytes)
+	}
+	return ""
+}
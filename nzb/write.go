@@ -0,0 +1,87 @@
+package nzb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	header = xml.Header + `<!DOCTYPE nzb PUBLIC "-//newzBin//DTD NZB 1.1//EN" "http://www.newzbin.com/DTD/nzb/nzb-1.1.dtd">` + "\n"
+	xmlns  = "http://www.newzbin.com/DTD/2003/nzb"
+)
+
+// WriteTo writes n as an NZB 1.1 document in UTF-8. Meta values are
+// written in order of type and each file's segments in number order.
+func (n *NZB) WriteTo(w io.Writer) (int64, error) {
+	doc := document{Xmlns: xmlns}
+	types := make([]string, 0, len(n.Meta))
+	for t := range n.Meta {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		for _, v := range n.Meta[t] {
+			doc.Meta = append(doc.Meta, docMeta{Type: t, Value: v})
+		}
+	}
+	for _, f := range n.Files {
+		df := docFile{Poster: f.Poster, Date: f.Date, Subject: f.Subject, Groups: f.Groups}
+		for _, s := range f.Segments {
+			df.Segments = append(df.Segments, docSegment{Bytes: s.Bytes, Number: s.Number, MessageID: s.MessageID})
+		}
+		sort.SliceStable(df.Segments, func(i, j int) bool {
+			return df.Segments[i].Number < df.Segments[j].Number
+		})
+		doc.Files = append(doc.Files, df)
+	}
+	out, err := xml.MarshalIndent(doc, "", " ")
+	if err != nil {
+		return 0, fmt.Errorf("nzb: %w", err)
+	}
+	m, err := io.WriteString(w, header)
+	if err != nil {
+		return int64(m), err
+	}
+	k, err := w.Write(append(out, '\n'))
+	return int64(m + k), err
+}
+
+// Subject returns the conventional subject for a part of a yenc post,
+// `"name" yEnc (part/total)`, which File.Name understands. The name is
+// quoted as is, newsreaders don't unescape it.
+func Subject(name string, part, total int) string {
+	return fmt.Sprintf("\"%s\" yEnc (%d/%d)", name, part, total)
+}
+
+// Track returns a writer for an article of f that counts the bytes
+// written to w and adds the segment to f when closed, closing w too if
+// it is an io.Closer. It suits the writers given to a
+// yenc.MultipartEncoder, which closes them as each part is finished.
+// Segments of a file must be tracked from one goroutine at a time.
+func (f *File) Track(number int, messageID string, w io.Writer) io.WriteCloser {
+	return &tracker{f: f, w: w, segment: Segment{Number: number, MessageID: messageID}}
+}
+
+type tracker struct {
+	f       *File
+	w       io.Writer
+	segment Segment
+}
+
+func (t *tracker) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.segment.Bytes += int64(n)
+	return n, err
+}
+
+func (t *tracker) Close() error {
+	if c, ok := t.w.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	t.f.Segments = append(t.f.Segments, t.segment)
+	return nil
+}
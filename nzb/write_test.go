@@ -0,0 +1,95 @@
+package nzb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chrisfarms/yenc"
+)
+
+// an article kept in memory
+type article struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (a *article) Close() error {
+	a.closed = true
+	return nil
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("posting "), 20000)
+	f := &File{Poster: "René <rene@example.com>", Date: 1700000000, Subject: Subject("data.bin", 1, 4), Groups: []string{"alt.binaries.test"}}
+	var articles []*article
+	m := &yenc.MultipartEncoder{
+		PartSize: 50000,
+		Preamble: func(part, total int) []string {
+			return []string{"Subject: " + Subject("data.bin", part, total), ""}
+		},
+	}
+	err := m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		a := new(article)
+		articles = append(articles, a)
+		return f.Track(part, fmt.Sprintf("part%d@example.com", part), a), nil
+	})
+	if err != nil {
+		t.Fatal("expected to encode: " + err.Error())
+	}
+	if len(f.Segments) != 4 || !articles[3].closed || f.Segments[3].Bytes != int64(articles[3].Len()) {
+		t.Fatalf("unexpected segments %+v", f.Segments)
+	}
+
+	var out bytes.Buffer
+	n := &NZB{Meta: map[string][]string{"title": {"Data & more"}}, Files: []*File{f}}
+	if written, err := n.WriteTo(&out); err != nil || written != int64(out.Len()) {
+		t.Fatalf("expected to write %d bytes got %d: %v", out.Len(), written, err)
+	}
+	if !strings.HasPrefix(out.String(), "<?xml") || !strings.Contains(out.String(), "<!DOCTYPE nzb") {
+		t.Errorf("expected xml and doctype headers got %q", out.String()[:100])
+	}
+	parsed, err := Parse(&out)
+	if err != nil {
+		t.Fatal("expected to parse what was written: " + err.Error())
+	}
+	pf := parsed.Files[0]
+	if parsed.Meta["title"][0] != "Data & more" || pf.Poster != f.Poster || pf.Date != f.Date || pf.Name() != "data.bin" {
+		t.Errorf("unexpected document %+v %+v", parsed, pf)
+	}
+	if len(pf.Segments) != 4 || pf.Segments[2] != f.Segments[2] {
+		t.Errorf("unexpected segments %+v", pf.Segments)
+	}
+
+	// the articles decode to parts that match the NZB
+	var posted bytes.Buffer
+	for _, a := range articles {
+		posted.Write(a.Bytes())
+	}
+	files, err := (&yenc.Extractor{Dir: t.TempDir()}).Extract(&posted)
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if found := parsed.Verify(files, 0); len(found) > 0 {
+		t.Errorf("expected no discrepancies got %v", found)
+	}
+}
+
+func TestSubjectRoundTrip(t *testing.T) {
+	for _, name := range []string{"data.bin", `say "hi".bin`, `back\slash.bin`} {
+		n := &NZB{Files: []*File{{Subject: Subject(name, 1, 2), Segments: []Segment{{Number: 1, Bytes: 100, MessageID: "a@example.com"}}}}}
+		var out bytes.Buffer
+		if _, err := n.WriteTo(&out); err != nil {
+			t.Fatal("expected to write: " + err.Error())
+		}
+		parsed, err := Parse(&out)
+		if err != nil {
+			t.Fatal("expected to parse what was written: " + err.Error())
+		}
+		if got := parsed.Files[0].Name(); got != name {
+			t.Errorf("expected name %q got %q from subject %q", name, got, parsed.Files[0].Subject)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"regexp"
+)
+
+// Option configures the decoder
+type Option func(*decoder)
+
+// WithCRC64 computes a CRC-64 (ECMA) of each part's decoded payload
+// while decoding, available as Part.CRC64. Useful as a stronger key
+// for dedup stores without a second pass over the data.
+func WithCRC64() Option {
+	return func(d *decoder) {
+		d.withCRC64 = true
+	}
+}
+
+// WithCRCWarnings records pcrc32 and crc32 mismatches in Part.Warnings
+// instead of failing, so damaged data is still returned and can be
+// kept for PAR2 repair. Size mismatches are still errors.
+func WithCRCWarnings() Option {
+	return func(d *decoder) {
+		d.crcWarnings = true
+	}
+}
+
+// WithSkipCRC neither computes nor checks crcs, for trusted input where
+// the speed matters more. Sizes are still checked.
+func WithSkipCRC() Option {
+	return func(d *decoder) {
+		d.skipCRC = true
+	}
+}
+
+// WithMaxPartSize fails a part whose headers declare more than n bytes,
+// or whose data turns out to be longer, before decoding past the limit,
+// with a *LimitError. Zero or less turns the limit off. Without it
+// parts held in memory are limited to DefaultMaxPartSize.
+func WithMaxPartSize(n int64) Option {
+	return func(d *decoder) {
+		d.maxPartSize = max(n, 0)
+	}
+}
+
+// WithLenient decodes as much as possible from damaged or sloppy input:
+// crc and size mismatches become warnings, see WithCRCWarnings and
+// SizeAccept, and WithResync, WithInferOffsets and WithPartial are
+// turned on
+func WithLenient() Option {
+	return func(d *decoder) {
+		d.partial = true
+		d.crcWarnings = true
+		d.sizePolicy = SizeAccept
+		d.resync = true
+		d.inferOffsets = true
+	}
+}
+
+// WithPartial has Decode and DecodeResult return what was decoded along
+// with the error when a part fails its checks or the input ends inside
+// one, instead of nothing, so damaged data can still go to a repair
+// tool. The part that failed is the last in the result and holds
+// whatever data was recovered.
+func WithPartial() Option {
+	return func(d *decoder) {
+		d.partial = true
+	}
+}
+
+// SizePolicy decides what happens when a part's decoded length differs
+// from the size in its trailer
+type SizePolicy int
+
+const (
+	// fail the decode (the default)
+	SizeError SizePolicy = iota
+	// cut a part that is too long down to the declared size
+	SizeTruncate
+	// fill a part that is too short with zeros up to the declared size
+	SizePad
+	// keep the part as it is
+	SizeAccept
+)
+
+// WithSizePolicy sets how size mismatches are handled. Apart from
+// SizeError the mismatch is recorded in Part.Warnings. Truncating and
+// padding need the part body, so when streaming with NewDecoder they
+// behave like SizeAccept.
+func WithSizePolicy(policy SizePolicy) Option {
+	return func(d *decoder) {
+		d.sizePolicy = policy
+	}
+}
+
+// WithYEnc2 also reads the =ybegin2, =ypart2 and =yend2 lines of the
+// yenc 2 draft, which are otherwise passed over as not being yenc. Their
+// keywords are read as for version 1, with any the draft adds kept in
+// Part.Headers and Part.TrailerHeaders, and Part.Version is 2.
+func WithYEnc2() Option {
+	return func(d *decoder) {
+		d.yenc2 = true
+	}
+}
+
+// WhitespacePolicy decides what happens to spaces and tabs ending a body
+// line. Encoders must escape them, as transports may add or drop them,
+// so finding them means a broken encoder or a mangled article.
+type WhitespacePolicy int
+
+const (
+	// decode them as data (the default), as a broken encoder most
+	// likely meant
+	WhitespaceKeep WhitespacePolicy = iota
+	// drop them, taking them to have been added in transit
+	WhitespaceStrip
+	// fail the decode with ErrTrailingWhitespace
+	WhitespaceError
+)
+
+// WithWhitespacePolicy sets how unescaped spaces and tabs at the end of
+// a body line are handled. Either way lines holding them are counted in
+// Part.Stats.TrailingWhitespace, so a crc failure they cause can be told
+// apart from other damage.
+func WithWhitespacePolicy(policy WhitespacePolicy) Option {
+	return func(d *decoder) {
+		d.whitespacePolicy = policy
+	}
+}
+
+// EscapePolicy decides what an = ending a body line escapes. The spec
+// has encoders keep an escape on one line, but some split it across the
+// line break.
+type EscapePolicy int
+
+const (
+	// escape the first char of the next line (the default), as an
+	// encoder that split the escape meant
+	EscapeNextLine EscapePolicy = iota
+	// escape the line break itself, the CR or LF that follows the =
+	EscapeLineBreak
+	// fail the decode with ErrEscapeAtLineEnd
+	EscapeError
+)
+
+// WithEscapePolicy sets how an = ending a body line is decoded. Lines
+// ending that way are counted in Part.Stats.LineEndEscapes.
+func WithEscapePolicy(policy EscapePolicy) Option {
+	return func(d *decoder) {
+		d.escapePolicy = policy
+	}
+}
+
+// number of unescaped spaces and tabs ending line
+func (d *decoder) trailingWhitespace(line []byte) int {
+	if len(line) == 0 || (line[len(line)-1] != ' ' && line[len(line)-1] != '\t') {
+		return 0
+	}
+	end := len(bytes.TrimRight(line, " \t"))
+	n := len(line) - end
+	// the first of them is escaped if an odd run of = comes before it,
+	// counting an escape left open at the end of the line before when
+	// the run starts the line
+	eq := end - len(bytes.TrimRight(line[:end], "="))
+	if eq == end && d.awaitingSpecial {
+		eq++
+	}
+	if eq%2 == 1 {
+		n--
+	}
+	return n
+}
+
+// WithRaw keeps a copy of each part's original encoded bytes, headers
+// and trailer included, in Part.Raw so the article can be relayed
+// exactly as it was received after being verified
+func WithRaw() Option {
+	return func(d *decoder) {
+		d.withRaw = true
+	}
+}
+
+// WithRawTee copies the encoded input to w as the decoder reads it,
+// everything from article headers to trailing lines, so a caching proxy
+// can store an article verbatim while it is verified. Unlike WithRaw
+// nothing is held in memory. Input read ahead of the part being decoded
+// is copied too, and an error writing to w stops the decode.
+func WithRawTee(w io.Writer) Option {
+	return func(d *decoder) {
+		d.rawTee = w
+	}
+}
+
+// WithHeaderLines keeps the =ybegin, =ypart and =yend lines of each
+// part exactly as they were read, in Part.HeaderLine, PartLine and
+// TrailerLine
+func WithHeaderLines() Option {
+	return func(d *decoder) {
+		d.withLines = true
+	}
+}
+
+// WithResync also finds a =ybegin header that doesn't start its line,
+// for input where a gateway joined lines and left junk in front of it.
+// Such headers are counted in Part.Stats.Resyncs.
+func WithResync() Option {
+	return func(d *decoder) {
+		d.resync = true
+	}
+}
+
+// WithInferOffsets handles multipart posts whose =ypart line is missing
+// or has unusable offsets, working them out from the sizes of the parts
+// before, which needs the parts of a file to arrive in order. Inferred
+// offsets are noted in Part.Warnings.
+func WithInferOffsets() Option {
+	return func(d *decoder) {
+		d.inferOffsets = true
+	}
+}
+
+// LineFunc sees each raw line after the headers, with the line ending
+// stripped, before it is decoded. The returned line is used in its
+// place so quirks can be repaired; return line itself to leave it be.
+type LineFunc func(p *Part, line []byte) []byte
+
+// ChunkFunc sees the decoded bytes of each line. b must not be kept
+// after the call returns.
+type ChunkFunc func(p *Part, b []byte)
+
+// WithLineFunc adds f to the functions run on each raw line, in the
+// order they were added
+func WithLineFunc(f LineFunc) Option {
+	return func(d *decoder) {
+		d.lineFuncs = append(d.lineFuncs, f)
+	}
+}
+
+// WithChunkFunc adds f to the functions run on each decoded line, in
+// the order they were added
+func WithChunkFunc(f ChunkFunc) Option {
+	return func(d *decoder) {
+		d.chunkFuncs = append(d.chunkFuncs, f)
+	}
+}
+
+// Allocator supplies the buffers that part bodies and decoding scratch
+// space are kept in, for applications managing their own memory
+type Allocator interface {
+	// Get returns a buffer of length n
+	Get(n int) []byte
+	// Put hands back a buffer from Get that is no longer in use
+	Put(b []byte)
+}
+
+// WithAllocator has the decoder take its buffers from a rather than
+// allocating them itself
+func WithAllocator(a Allocator) Option {
+	return func(d *decoder) {
+		d.alloc = a
+	}
+}
+
+// PartInfo holds what the headers say about a part, before its body
+// has been read
+type PartInfo struct {
+	Name string
+	// part number and total, zero when not given
+	Number, Total int
+	// size of the whole file
+	Size int64
+	// range of the file this part covers, zero for single part data
+	Begin, End int64
+}
+
+// WithFilter decodes only the parts for which keep returns true. The
+// bodies of other parts are read past without being decoded or hashed,
+// and checks on the file as a whole are skipped when any part was.
+// Given more than once a part must pass every filter.
+func WithFilter(keep func(PartInfo) bool) Option {
+	return func(d *decoder) {
+		prev := d.filter
+		d.filter = func(p *Part) bool {
+			if prev != nil && !prev(p) {
+				return false
+			}
+			return keep(d.partInfo(p))
+		}
+	}
+}
+
+// the filter set by opts, for parts found without a decoder, true for
+// every part when there is none
+func filterOf(opts []Option) func(p *Part) bool {
+	d := new(decoder)
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.filter == nil {
+		return func(*Part) bool { return true }
+	}
+	return d.filter
+}
+
+func (d *decoder) partInfo(p *Part) PartInfo {
+	return PartInfo{
+		Name:   p.Name,
+		Number: p.Number,
+		Total:  d.total,
+		Size:   p.HeaderSize,
+		Begin:  p.Begin,
+		End:    p.End,
+	}
+}
+
+// WithParts decodes only the parts with the given numbers, see
+// WithFilter. Single part data has no part number so is always skipped.
+func WithParts(numbers ...int) Option {
+	return WithFilter(func(p PartInfo) bool {
+		for _, n := range numbers {
+			if p.Number == n {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithNameGlob decodes only the parts whose name matches a shell
+// pattern such as "*.nfo", see path.Match. A malformed pattern matches
+// nothing.
+func WithNameGlob(pattern string) Option {
+	return WithFilter(func(p PartInfo) bool {
+		ok, _ := path.Match(pattern, p.Name)
+		return ok
+	})
+}
+
+// WithNameRegexp decodes only the parts whose name matches re
+func WithNameRegexp(re *regexp.Regexp) Option {
+	return WithFilter(func(p PartInfo) bool {
+		return re.MatchString(p.Name)
+	})
+}
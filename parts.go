@@ -0,0 +1,144 @@
+package yenc
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+)
+
+// Parts are always returned in the order they were read from the
+// input. These helpers put them in file order for assembly.
+
+// SortPartsByNumber sorts parts by part number. Parts sharing a number
+// keep their relative order.
+func SortPartsByNumber(parts []*Part) {
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+}
+
+// SortPartsByBegin sorts parts by their offset in the file. Parts
+// sharing an offset keep their relative order.
+func SortPartsByBegin(parts []*Part) {
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].Begin < parts[j].Begin
+	})
+}
+
+// MissingParts returns, in order, the numbers from 1 to total that no
+// part has. When total is zero the highest part number seen is used,
+// so only gaps can be found.
+func MissingParts(parts []*Part, total int) []int {
+	have := make(map[int]bool)
+	highest := 0
+	for _, p := range parts {
+		have[p.Number] = true
+		highest = max(highest, p.Number)
+	}
+	if total == 0 {
+		total = highest
+	}
+	var missing []int
+	for n := 1; n <= total; n++ {
+		if !have[n] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// PayloadCRC32 returns the crc32 of the part's decoded data, whether or
+// not its trailer gave one
+func (p *Part) PayloadCRC32() uint32 {
+	if p.Body == nil && p.crcHash != nil {
+		// streamed, the body was never kept
+		return p.crcHash.Sum32()
+	}
+	return crc32.ChecksumIEEE(p.Body)
+}
+
+// crc32 of the part's body, from the hash the decoder kept while the
+// body is still the data it hashed, so it needn't be hashed again. A
+// body a size policy truncated or padded is hashed afresh.
+func (p *Part) bodyCRC32() uint32 {
+	if p.crcHash != nil && int64(len(p.Body)) == p.decoded {
+		return p.crcHash.Sum32()
+	}
+	return crc32.ChecksumIEEE(p.Body)
+}
+
+// SameMetadata reports whether p and q describe the same part of the
+// same file: name, number, range and sizes all agree
+func (p *Part) SameMetadata(q *Part) bool {
+	return p.Name == q.Name && p.Number == q.Number &&
+		p.Begin == q.Begin && p.End == q.End &&
+		p.Size == q.Size && p.HeaderSize == q.HeaderSize
+}
+
+// SamePayload reports whether p and q decoded to the same data, going
+// by size and crc32
+func (p *Part) SamePayload(q *Part) bool {
+	return p.DecodedLen() == q.DecodedLen() && p.PayloadCRC32() == q.PayloadCRC32()
+}
+
+// DecodedLen returns the length of the part's decoded data, whether or
+// not its body was kept, so it holds after Release or when streamed
+func (p *Part) DecodedLen() int64 {
+	if p.Body == nil {
+		return p.decoded
+	}
+	return int64(len(p.Body))
+}
+
+// Merge fills in the metadata p lacks from q, such as a trailer size or
+// crc missing from one provider's copy of a segment, or a range only
+// the other copy's headers gave. Values present in both must agree;
+// conflicts are returned as an error and leave p unchanged.
+func (p *Part) Merge(q *Part) error {
+	var conflicts []string
+	check := func(what string, a, b int64) {
+		if a != 0 && b != 0 && a != b {
+			conflicts = append(conflicts, fmt.Sprintf("%s %d != %d", what, a, b))
+		}
+	}
+	if p.Name != "" && q.Name != "" && p.Name != q.Name {
+		conflicts = append(conflicts, fmt.Sprintf("name %q != %q", p.Name, q.Name))
+	}
+	check("number", int64(p.Number), int64(q.Number))
+	check("begin", p.Begin, q.Begin)
+	check("end", p.End, q.End)
+	check("size", p.Size, q.Size)
+	check("file size", p.HeaderSize, q.HeaderSize)
+	check("total", int64(p.Total), int64(q.Total))
+	check("crc32", int64(p.CRC32), int64(q.CRC32))
+	check("file crc32", int64(p.FileCRC32), int64(q.FileCRC32))
+	if len(conflicts) > 0 {
+		return fmt.Errorf("yenc: parts disagree: %s", strings.Join(conflicts, ", "))
+	}
+	if p.Name == "" {
+		p.Name, p.RawName = q.Name, q.RawName
+	}
+	if p.Number == 0 {
+		p.Number = q.Number
+	}
+	if p.Begin == 0 {
+		p.Begin, p.End = q.Begin, q.End
+	}
+	if p.Size == 0 {
+		p.Size = q.Size
+	}
+	if p.HeaderSize == 0 {
+		p.HeaderSize = q.HeaderSize
+	}
+	if p.Total == 0 {
+		p.Total = q.Total
+	}
+	if p.CRC32 == 0 {
+		p.CRC32 = q.CRC32
+	}
+	if p.FileCRC32 == 0 {
+		p.FileCRC32 = q.FileCRC32
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSortParts(t *testing.T) {
+	parts := []*Part{
+		{Number: 3, Begin: 7},
+		{Number: 1, Begin: 1, Name: "first"},
+		{Number: 2, Begin: 4},
+		{Number: 1, Begin: 1, Name: "repeat"},
+	}
+	SortPartsByNumber(parts)
+	if parts[0].Name != "first" || parts[1].Name != "repeat" || parts[3].Number != 3 {
+		t.Errorf("expected stable sort by number got %v", numbers(parts))
+	}
+	parts[0], parts[3] = parts[3], parts[0]
+	SortPartsByBegin(parts)
+	if parts[0].Begin != 1 || parts[2].Begin != 4 || parts[3].Begin != 7 {
+		t.Errorf("expected sort by begin got %v", numbers(parts))
+	}
+}
+
+func TestMissingParts(t *testing.T) {
+	parts := []*Part{{Number: 4}, {Number: 1}, {Number: 2}}
+	if m := fmt.Sprint(MissingParts(parts, 6)); m != "[3 5 6]" {
+		t.Errorf("expected [3 5 6] missing got %s", m)
+	}
+	if m := fmt.Sprint(MissingParts(parts, 0)); m != "[3]" {
+		t.Errorf("expected gap [3] got %s", m)
+	}
+}
+
+func numbers(parts []*Part) []int {
+	var n []int
+	for _, p := range parts {
+		n = append(n, p.Number)
+	}
+	return n
+}
+
+func TestPartCompareAndMerge(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	a, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	b, _ := Decode(bytes.NewReader(raw))
+	if !a.SameMetadata(b) || !a.SamePayload(b) {
+		t.Errorf("expected two decodes of the same part to match")
+	}
+	if a.PayloadCRC32() != 0xbfae5c0b {
+		t.Errorf("expected payload crc bfae5c0b got %08x", a.PayloadCRC32())
+	}
+	b.Body[0]++
+	if a.SamePayload(b) {
+		t.Errorf("expected changed payload not to match")
+	}
+	// a copy whose trailer was lost
+	partial := &Part{Name: a.Name, Number: 1, Begin: 1, End: 11250}
+	if err := partial.Merge(a); err != nil {
+		t.Fatal("expected to merge: " + err.Error())
+	}
+	if partial.Size != 11250 || partial.CRC32 != 0xbfae5c0b || !partial.SameMetadata(a) {
+		t.Errorf("expected trailer values to be merged got %+v", partial)
+	}
+	other := &Part{Name: a.Name, Number: 2}
+	if err := other.Merge(a); err == nil || other.Size != 0 {
+		t.Errorf("expected conflicting part numbers to fail")
+	}
+}
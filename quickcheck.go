@@ -0,0 +1,86 @@
+package yenc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// QuickCheck checks the structure of a cached article without decoding
+// it: a =ybegin header must be present, the =yend trailer must carry the
+// matching part number and the declared size must be plausible for the
+// amount of encoded data between them. CRCs are not checked. The read
+// position of r is restored afterwards.
+func QuickCheck(r io.ReadSeeker) error {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	err = QuickCheckAt(seekReaderAt{r}, size)
+	if _, serr := r.Seek(pos, io.SeekStart); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// QuickCheckAt is like QuickCheck but works on the first size bytes of r.
+func QuickCheckAt(r io.ReaderAt, size int64) error {
+	// read the headers from the front
+	cr := &countingReader{r: io.NewSectionReader(r, 0, size)}
+	d := &decoder{buf: bufio.NewReader(cr), part: new(Part)}
+	if err := d.readHeader(); err != nil {
+		return fmt.Errorf("yenc: no =ybegin header found: %w", err)
+	}
+	if d.multipart {
+		if err := d.readPartHeader(); err != nil {
+			return fmt.Errorf("yenc: no =ypart header found: %w", err)
+		}
+	}
+	bodyStart := cr.n - int64(d.buf.Buffered())
+	// and the trailer from the back
+	line, trailerStart, err := findTrailerLine(r, size)
+	if err != nil {
+		return err
+	}
+	if trailerStart < bodyStart {
+		return fmt.Errorf("yenc: =yend found before end of headers")
+	}
+	t := parseTrailerLine(string(line))
+	p := d.part
+	if d.multipart && t.Part != p.Number {
+		return fmt.Errorf("yenc: =yend part %d does not match header part %d", t.Part, p.Number)
+	}
+	// sizes must agree with the headers
+	if p.HeaderSize > 0 && t.Size > p.HeaderSize {
+		return fmt.Errorf("yenc: trailer size %d larger than file size %d", t.Size, p.HeaderSize)
+	}
+	if d.multipart && p.End > 0 && p.End-p.Begin+1 != t.Size {
+		return fmt.Errorf("yenc: trailer size %d does not match part range %d-%d", t.Size, p.Begin, p.End)
+	}
+	// every decoded byte takes one or two encoded bytes, plus line endings
+	cols := int64(p.Line)
+	if cols <= 0 {
+		cols = 128
+	}
+	encoded := trailerStart - bodyStart
+	if encoded < t.Size || encoded > 2*t.Size+2*(2*t.Size/cols+1) {
+		return fmt.Errorf("yenc: %d encoded bytes implausible for declared size %d", encoded, t.Size)
+	}
+	return nil
+}
+
+// counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,38 @@
+package yenc
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQuickCheck(t *testing.T) {
+	for _, name := range []string{"singlepart_test.yenc", "multipart_test.yenc"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal("could not open " + name + " for testing")
+		}
+		if err := QuickCheck(f); err != nil {
+			t.Errorf("%s: expected to pass quick check: %v", name, err)
+		}
+		f.Close()
+	}
+}
+
+func TestQuickCheckBroken(t *testing.T) {
+	inputs := []string{
+		// no header
+		"abc\r\n=yend size=3\r\n",
+		// no trailer
+		"=ybegin line=128 size=3 name=x\r\nabc\r\n",
+		// wrong part number
+		"=ybegin part=2 line=128 size=6 name=x\r\n=ypart begin=4 end=6\r\nabc\r\n=yend size=3 part=1\r\n",
+		// size claims more data than is present
+		"=ybegin line=128 size=300 name=x\r\nabc\r\n=yend size=300\r\n",
+	}
+	for _, in := range inputs {
+		if err := QuickCheck(strings.NewReader(in)); err == nil {
+			t.Errorf("expected quick check to fail for %q", in)
+		}
+	}
+}
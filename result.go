@@ -0,0 +1,68 @@
+package yenc
+
+import "io"
+
+// Result describes everything found in a yenc stream
+type Result struct {
+	// the highest yenc version of the parts' headers, 2 if any were
+	// from the draft, see WithYEnc2
+	Version int
+	// whether the stream held multipart data
+	Multipart bool
+	// number of parts from the header, zero if not given
+	Total int
+	// whether a whole file crc32 was given in a trailer, and its value
+	FileCRCPresent bool
+	FileCRC        uint32
+	// every decoded part in the order they were read
+	Parts []*Part
+}
+
+// DecodeResult decodes every part in input, returning them together
+// with the multipart details of the stream
+func DecodeResult(input io.Reader, opts ...Option) (*Result, error) {
+	d := newDecoder(input, opts...)
+	defer d.release()
+	err := d.run()
+	if err == io.EOF {
+		err = d.finish()
+	}
+	if err != nil {
+		if d.partial && len(d.parts) > 0 {
+			return d.result(), err
+		}
+		return nil, err
+	}
+	return d.result(), nil
+}
+
+// DecodeAll decodes every part in input and returns them in the order
+// they were read
+func DecodeAll(input io.Reader, opts ...Option) ([]*Part, error) {
+	res, err := DecodeResult(input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res.Parts, nil
+}
+
+func (d *decoder) result() *Result {
+	r := &Result{
+		Multipart:      d.multipart,
+		Total:          d.total,
+		FileCRCPresent: d.crc32 > 0,
+		FileCRC:        d.crc32,
+		Parts:          d.parts,
+	}
+	for _, p := range d.parts {
+		r.Version = max(r.Version, p.Version)
+	}
+	return r
+}
+
+// Release releases the bodies of all parts, see Part.Release
+func (r *Result) Release() {
+	for _, p := range r.Parts {
+		p.Release()
+	}
+}
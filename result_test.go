@@ -0,0 +1,42 @@
+package yenc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeResult(t *testing.T) {
+	data := encodeTestData(5000)
+	var all bytes.Buffer
+	for _, a := range encodeParts(t, &MultipartEncoder{PartSize: 2000}, data) {
+		all.Write(a.Bytes())
+	}
+	res, err := DecodeResult(&all)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if !res.Multipart || res.Total != 3 || len(res.Parts) != 3 || !res.FileCRCPresent {
+		t.Errorf("unexpected result %+v", res)
+	}
+	var body []byte
+	for _, p := range res.Parts {
+		body = append(body, p.Body...)
+	}
+	if !bytes.Equal(body, data) {
+		t.Errorf("parts did not match input")
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	var all bytes.Buffer
+	for _, a := range encodeParts(t, &MultipartEncoder{PartSize: 2000}, encodeTestData(5000)) {
+		all.Write(a.Bytes())
+	}
+	parts, err := DecodeAll(&all)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if len(parts) != 3 || parts[2].Number != 3 {
+		t.Errorf("expected all 3 parts got %d", len(parts))
+	}
+}
@@ -0,0 +1,187 @@
+package yenc
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Rewrite decodes input leniently and writes every part back to w in
+// canonical form: CRLF line endings, keywords in the usual order and
+// sizes, offsets and crcs recomputed from the data. Problems found in
+// the input are left in each part's Warnings.
+func (enc *Encoding) Rewrite(w io.Writer, input io.Reader) (*Result, error) {
+	res, err := DecodeResult(input, WithCRCWarnings(), WithSizePolicy(SizeAccept), WithResync(), WithInferOffsets())
+	if err != nil {
+		return nil, err
+	}
+	fileCRC, complete := res.completeCRC()
+	for i, p := range res.Parts {
+		e := newEncoder(w, p.Name, int64(len(p.Body)))
+		e.line = enc.lineLength()
+		if res.Multipart {
+			e.size = p.HeaderSize
+			e.part, e.total = p.Number, res.Total
+			e.begin = max(p.Begin, 1)
+			e.end = e.begin + int64(len(p.Body)) - 1
+			if complete && i == len(res.Parts)-1 {
+				e.fileCRC, e.hasFileCRC = fileCRC, true
+			}
+		}
+		if _, err := e.Write(p.Body); err != nil {
+			return res, err
+		}
+		if err := e.Close(); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// the crc of the whole file if every part of a multipart set is present
+func (r *Result) completeCRC() (uint32, bool) {
+	if !r.Multipart || r.Total == 0 || len(r.Parts) != r.Total {
+		return 0, false
+	}
+	parts := append([]*Part(nil), r.Parts...)
+	SortPartsByNumber(parts)
+	if len(MissingParts(parts, r.Total)) > 0 {
+		return 0, false
+	}
+	var crc uint32
+	for _, p := range parts {
+		crc = crc32.Update(crc, crc32.IEEETable, p.Body)
+	}
+	return crc, true
+}
+
+// RewriteTrailers copies input to w, replacing each =yend line with
+// one carrying the size and crcs of the data actually present and
+// adding trailers to parts that end without one. Header and body lines
+// are copied untouched. The whole file crc is only written for the last
+// part of a set when all earlier parts came before it in order. It
+// returns the number of trailers that were changed or added.
+func RewriteTrailers(w io.Writer, input io.Reader) (int, error) {
+	t := &trailerWriter{w: bufio.NewWriter(w)}
+	buf := bufio.NewReader(input)
+	for {
+		s, err := buf.ReadString('\n')
+		if len(s) > 0 {
+			t.line(s)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return t.changed, err
+		}
+	}
+	if t.inBlock {
+		t.trailer("", "\r\n")
+	}
+	if t.err == nil {
+		t.err = t.w.Flush()
+	}
+	return t.changed, t.err
+}
+
+// trailerWriter tracks the block being copied by RewriteTrailers
+type trailerWriter struct {
+	w       *bufio.Writer
+	changed int
+	err     error
+	// current block
+	inBlock bool
+	// decodes body lines into scratch space, the output keeps the originals
+	d       decoder
+	scratch []byte
+	part    int64
+	size    int64
+	end     int64
+	n       int64
+	crc     uint32
+	// running crc of the file while its parts arrive in order
+	fileCRC  uint32
+	fileNext int64
+}
+
+func (t *trailerWriter) write(s string) {
+	if t.err == nil {
+		_, t.err = t.w.WriteString(s)
+	}
+}
+
+func (t *trailerWriter) line(s string) {
+	header, _ := trimLinePreamble(s)
+	switch {
+	case strings.HasPrefix(header, "=ybegin"):
+		if t.inBlock {
+			t.trailer("", "\r\n")
+		}
+		h := parseKeywords(header)
+		t.inBlock = true
+		t.part, _ = strconv.ParseInt(h["part"], 10, 64)
+		t.size, _ = strconv.ParseInt(h["size"], 10, 64)
+		t.end = t.size
+		t.n, t.crc = 0, 0
+		t.d = decoder{part: new(Part)}
+		if t.part == 0 {
+			t.fileCRC, t.fileNext = 0, 1
+		}
+		t.write(s)
+	case !t.inBlock:
+		t.write(s)
+	case strings.HasPrefix(s, "=ypart"):
+		h := parseKeywords(s)
+		begin, _ := strconv.ParseInt(h["begin"], 10, 64)
+		t.end, _ = strconv.ParseInt(h["end"], 10, 64)
+		if begin == 1 {
+			t.fileCRC, t.fileNext = 0, 1
+		}
+		if begin != t.fileNext {
+			// out of order, the file crc can't be worked out
+			t.fileNext = -1
+		}
+		t.write(s)
+	case strings.HasPrefix(s, "=yend"):
+		body := strings.TrimRight(s, "\r\n")
+		t.trailer(body, s[len(body):])
+	default:
+		t.scratch = append(t.scratch[:0], strings.TrimRight(s, "\r\n")...)
+		b := t.d.decode(t.scratch)
+		t.n += int64(len(b))
+		t.crc = crc32.Update(t.crc, crc32.IEEETable, b)
+		if t.fileNext > 0 {
+			t.fileCRC = crc32.Update(t.fileCRC, crc32.IEEETable, b)
+		}
+		t.write(s)
+	}
+}
+
+// write the trailer for the current block, old is the original =yend
+// line without its line ending, if there was one
+func (t *trailerWriter) trailer(old, eol string) {
+	t.inBlock = false
+	var s string
+	if t.part > 0 {
+		s = fmt.Sprintf("=yend size=%d part=%d pcrc32=%08x", t.n, t.part, t.crc)
+		if t.fileNext > 0 {
+			t.fileNext += t.n
+			if t.end == t.size && t.fileNext == t.size+1 {
+				s += fmt.Sprintf(" crc32=%08x", t.fileCRC)
+			}
+		}
+	} else {
+		s = fmt.Sprintf("=yend size=%d crc32=%08x", t.n, t.crc)
+	}
+	if eol == "" {
+		eol = "\r\n"
+	}
+	if s != old {
+		t.changed++
+	}
+	t.write(s + eol)
+}
@@ -0,0 +1,61 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	data := encodeTestData(5000)
+	var all bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &all, nil
+	})
+	// LF line endings, a wrong size in a trailer and a missing final newline
+	broken := bytes.ReplaceAll(all.Bytes(), []byte("\r\n"), []byte("\n"))
+	broken = bytes.Replace(broken, []byte("=yend size=2000 part=2"), []byte("=yend size=1999 part=2"), 1)
+	broken = bytes.TrimRight(broken, "\n")
+	var fixed bytes.Buffer
+	res, err := StdEncoding.Rewrite(&fixed, bytes.NewReader(broken))
+	if err != nil {
+		t.Fatal("expected to rewrite: " + err.Error())
+	}
+	if len(res.Parts[1].Warnings) == 0 {
+		t.Errorf("expected warning for wrong size")
+	}
+	report, err := Validate(bytes.NewReader(fixed.Bytes()))
+	if err != nil || !report.Valid() {
+		t.Errorf("expected rewritten output to validate got %v (%v)", report.Issues, err)
+	}
+	if !bytes.Equal(fixed.Bytes(), all.Bytes()) {
+		t.Errorf("expected rewrite to reproduce the original encoding")
+	}
+}
+
+func TestRewriteTrailers(t *testing.T) {
+	data := encodeTestData(5000)
+	var all bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &all, nil
+	})
+	// a wrong pcrc32, a trailer with no crcs and a missing trailer at the end
+	broken := bytes.Replace(all.Bytes(), []byte("part=1 pcrc32="), []byte("part=1 pcrc32=0"), 1)
+	i := bytes.Index(broken, []byte("=yend size=2000 part=2"))
+	j := bytes.IndexByte(broken[i:], '\r')
+	broken = append(broken[:i:i], append([]byte("=yend size=2000 part=2"), broken[i+j:]...)...)
+	broken = broken[:bytes.LastIndex(broken, []byte("=yend"))]
+	var fixed bytes.Buffer
+	n, err := RewriteTrailers(&fixed, bytes.NewReader(broken))
+	if err != nil {
+		t.Fatal("expected to rewrite trailers: " + err.Error())
+	}
+	if n != 3 {
+		t.Errorf("expected 3 trailers changed got %d", n)
+	}
+	if !bytes.Equal(fixed.Bytes(), all.Bytes()) {
+		t.Errorf("expected trailers to match the original encoding")
+	}
+}
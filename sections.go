@@ -0,0 +1,79 @@
+package yenc
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DecodeSections decodes the yenc blocks of one large input in
+// parallel. index holds the offset of each block in ra, in order, such
+// as the Offsets from a ContainerWriter's Index; each block runs to the
+// start of the next and the last to the end of ra. At most workers
+// blocks are decoded at once, GOMAXPROCS if workers is zero.
+//
+// The first part of each block is returned in index order. If any
+// block fails the error of the first failing one is returned, with the
+// parts of the others still filled in. The options are applied to every
+// block's decoder. Metrics given WithMetrics are summed over the blocks,
+// with Wall the time DecodeSections took. Other options holding state
+// are shared by the blocks: the writer given WithRawTee, an Allocator
+// and the funcs of WithFilter or a Tracer must be safe to use from
+// several goroutines, and a raw tee gets the blocks' bytes interleaved.
+func DecodeSections(ra io.ReaderAt, index []int64, workers int, opts ...Option) ([]*Part, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	parts := make([]*Part, len(index))
+	errs := make([]error, len(index))
+	metrics := metricsOf(opts)
+	started := time.Now()
+	var mu sync.Mutex
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(index)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if metrics == nil {
+					parts[i], errs[i] = Decode(section(ra, index, i), opts...)
+					continue
+				}
+				// each block times itself, the totals are added up after
+				var m Metrics
+				parts[i], errs[i] = Decode(section(ra, index, i), append(opts[:len(opts):len(opts)], WithMetrics(&m))...)
+				mu.Lock()
+				metrics.add(&m)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range index {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if metrics != nil {
+		metrics.Wall = time.Since(started)
+	}
+	for i, err := range errs {
+		if err != nil {
+			return parts, fmt.Errorf("yenc: section %d at offset %d: %w", i, index[i], err)
+		}
+	}
+	return parts, nil
+}
+
+// the i'th block of ra, reading of which is safe alongside the others
+// as ReaderAt allows parallel calls
+func section(ra io.ReaderAt, index []int64, i int) *io.SectionReader {
+	end := int64(math.MaxInt64)
+	if i+1 < len(index) {
+		end = index[i+1]
+	}
+	return io.NewSectionReader(ra, index[i], end-index[i])
+}
@@ -0,0 +1,71 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestDecodeSections(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewContainerWriter(&buf)
+	var files [][]byte
+	for i := 0; i < 20; i++ {
+		data := encodeTestData(1000 + 100*i)
+		w, _ := c.Create(fmt.Sprintf("file%02d.bin", i), int64(len(data)))
+		w.Write(data)
+		files = append(files, data)
+	}
+	c.Close()
+	var index []int64
+	for _, e := range c.Index() {
+		index = append(index, e.Offset)
+	}
+	ra := bytes.NewReader(buf.Bytes())
+	for _, workers := range []int{0, 1, 3, 50} {
+		parts, err := DecodeSections(ra, index, workers)
+		if err != nil {
+			t.Fatalf("%d workers: expected to decode: %v", workers, err)
+		}
+		for i, p := range parts {
+			if p.Name != fmt.Sprintf("file%02d.bin", i) || !bytes.Equal(p.Body, files[i]) {
+				t.Errorf("%d workers: unexpected part %d %q", workers, i, p.Name)
+			}
+		}
+	}
+
+	// a damaged block fails on its own
+	damaged := bytes.Replace(buf.Bytes(), []byte("name=file07.bin"), []byte("name=file07.bin\r\nxx"), 1)
+	for i := 8; i < len(index); i++ {
+		index[i] += 4
+	}
+	parts, err := DecodeSections(bytes.NewReader(damaged), index, 4)
+	if err == nil || parts[7] != nil || parts[8] == nil || parts[19] == nil {
+		t.Errorf("expected only section 7 to fail got %v", err)
+	}
+}
+
+func TestDecodeSectionsMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewContainerWriter(&buf)
+	total := 0
+	for i := 0; i < 20; i++ {
+		data := encodeTestData(1000 + 100*i)
+		w, _ := c.Create(fmt.Sprintf("file%02d.bin", i), int64(len(data)))
+		w.Write(data)
+		total += len(data)
+	}
+	c.Close()
+	var index []int64
+	for _, e := range c.Index() {
+		index = append(index, e.Offset)
+	}
+	// run with -race to catch blocks sharing the Metrics
+	var m Metrics
+	if _, err := DecodeSections(bytes.NewReader(buf.Bytes()), index, 4, WithMetrics(&m)); err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if m.DecodedBytes != int64(total) || m.EncodedBytes < m.DecodedBytes || m.Wall <= 0 {
+		t.Errorf("unexpected metrics %+v for %d bytes", m, total)
+	}
+}
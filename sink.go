@@ -0,0 +1,122 @@
+package yenc
+
+import "fmt"
+
+// Sink receives a file's decoded data as sequential chunks followed by
+// a final commit, which suits object stores that cannot seek such as
+// S3 multipart uploads
+type Sink interface {
+	// WriteChunk is called with consecutive chunks of the file, offset
+	// is where p starts and always follows on from the previous chunk
+	WriteChunk(offset int64, p []byte) error
+	// Commit is called once, after the last chunk
+	Commit() error
+}
+
+// SinkAssembler accepts the parts of one file in any order and feeds
+// them to a Sink in file order, holding back parts that arrive early.
+// The data of each part is checked against its pcrc32 as it is handed
+// to the sink, so the file needn't be read back to be verified.
+type SinkAssembler struct {
+	sink Sink
+	// total file size
+	size int64
+	// next offset the sink expects
+	next int64
+	// early parts keyed by offset
+	pending   map[int64]*Part
+	committed bool
+	// what was written, in file order
+	ranges []RangeStatus
+}
+
+// RangeStatus records the check of one part's data as it was written
+type RangeStatus struct {
+	// part number, and where its data sits in the file
+	Part           int
+	Offset, Length int64
+	// crc32 of the data written
+	CRC32 uint32
+	// whether the part's trailer gave a crc, and whether the data
+	// written matched it
+	HasCRC, Verified bool
+}
+
+// NewSinkAssembler returns an assembler writing a file of size bytes
+// to s. If size is zero the size from the first part's header is used.
+func NewSinkAssembler(s Sink, size int64) *SinkAssembler {
+	return &SinkAssembler{
+		sink:    s,
+		size:    size,
+		pending: make(map[int64]*Part),
+	}
+}
+
+// Add queues a decoded part, writing it and any parts that follow it to
+// the sink once everything before it has been written. Parts that have
+// already been written are ignored.
+func (a *SinkAssembler) Add(p *Part) error {
+	if a.committed {
+		return fmt.Errorf("yenc: part %d added after commit", p.Number)
+	}
+	if a.size == 0 {
+		a.size = p.HeaderSize
+	}
+	off := p.Begin - 1
+	if off < 0 {
+		off = 0
+	}
+	if off < a.next {
+		return nil
+	}
+	a.pending[off] = p
+	// flush everything that is now contiguous
+	for {
+		p, ok := a.pending[a.next]
+		if !ok {
+			break
+		}
+		delete(a.pending, a.next)
+		r := RangeStatus{Part: p.Number, Offset: a.next, Length: int64(len(p.Body)), CRC32: p.bodyCRC32(), HasCRC: p.CRC32 > 0}
+		r.Verified = r.HasCRC && r.CRC32 == p.CRC32
+		if err := a.sink.WriteChunk(a.next, p.Body); err != nil {
+			return err
+		}
+		a.ranges = append(a.ranges, r)
+		a.next += int64(len(p.Body))
+	}
+	if a.size > 0 && a.next >= a.size {
+		a.committed = true
+		return a.sink.Commit()
+	}
+	return nil
+}
+
+// Done reports whether the whole file has been written and committed
+func (a *SinkAssembler) Done() bool {
+	return a.committed
+}
+
+// Written returns how many bytes have been passed to the sink
+func (a *SinkAssembler) Written() int64 {
+	return a.next
+}
+
+// Ranges returns the check of each part written so far, in file order
+func (a *SinkAssembler) Ranges() []RangeStatus {
+	return append([]RangeStatus(nil), a.ranges...)
+}
+
+// Verified reports whether the whole file has been written and every
+// part of it matched its crc
+func (a *SinkAssembler) Verified() bool {
+	if !a.committed {
+		return false
+	}
+	for _, r := range a.ranges {
+		if !r.Verified {
+			return false
+		}
+	}
+	return true
+}
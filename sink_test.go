@@ -0,0 +1,98 @@
+package yenc
+
+import (
+	"bytes"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+type memorySink struct {
+	buf       bytes.Buffer
+	offsets   []int64
+	committed bool
+}
+
+func (s *memorySink) WriteChunk(offset int64, p []byte) error {
+	s.offsets = append(s.offsets, offset)
+	s.buf.Write(p)
+	return nil
+}
+
+func (s *memorySink) Commit() error {
+	s.committed = true
+	return nil
+}
+
+func TestSinkAssemblerOutOfOrder(t *testing.T) {
+	parts := []*Part{
+		{Number: 3, Begin: 7, End: 9, Body: []byte("ghi")},
+		{Number: 1, Begin: 1, End: 3, Body: []byte("abc")},
+		{Number: 1, Begin: 1, End: 3, Body: []byte("abc")},
+		{Number: 2, Begin: 4, End: 6, Body: []byte("def")},
+	}
+	s := new(memorySink)
+	a := NewSinkAssembler(s, 9)
+	for i, p := range parts {
+		if err := a.Add(p); err != nil {
+			t.Fatal("expected to add part: " + err.Error())
+		}
+		if i < 3 && a.Done() {
+			t.Fatalf("assembler done after %d parts", i+1)
+		}
+	}
+	if !a.Done() || !s.committed {
+		t.Fatal("expected assembler to commit after last part")
+	}
+	if s.buf.String() != "abcdefghi" {
+		t.Errorf("expected abcdefghi got %s", s.buf.String())
+	}
+	if len(s.offsets) != 3 || s.offsets[1] != 3 || s.offsets[2] != 6 {
+		t.Errorf("unexpected chunk offsets %v", s.offsets)
+	}
+}
+
+func TestSinkAssemblerVerifies(t *testing.T) {
+	parts := []*Part{
+		{Number: 2, Begin: 4, End: 6, Body: []byte("def"), CRC32: crc32.ChecksumIEEE([]byte("def"))},
+		{Number: 1, Begin: 1, End: 3, Body: []byte("abc"), CRC32: crc32.ChecksumIEEE([]byte("abc"))},
+	}
+	a := NewSinkAssembler(new(memorySink), 6)
+	for _, p := range parts {
+		a.Add(p)
+	}
+	ranges := a.Ranges()
+	if !a.Verified() || len(ranges) != 2 || ranges[1] != (RangeStatus{Part: 2, Offset: 3, Length: 3, CRC32: crc32.ChecksumIEEE([]byte("def")), HasCRC: true, Verified: true}) {
+		t.Errorf("expected both parts verified got %+v", ranges)
+	}
+
+	// data changed after decoding, or a part without a crc
+	parts[0].Body = []byte("dex")
+	parts[1].CRC32 = 0
+	a = NewSinkAssembler(new(memorySink), 6)
+	for _, p := range parts {
+		a.Add(p)
+	}
+	ranges = a.Ranges()
+	if a.Verified() || ranges[0].HasCRC || ranges[0].Verified || !ranges[1].HasCRC || ranges[1].Verified {
+		t.Errorf("expected neither part verified got %+v", ranges)
+	}
+}
+
+func TestSinkAssemblerPaddedPart(t *testing.T) {
+	// a trailer claiming more than was sent, padded with zeros
+	input := "=ybegin line=128 size=5 name=x\r\n\x8b\x8c\x8d\r\n=yend size=5\r\n"
+	p, err := Decode(strings.NewReader(input), WithSizePolicy(SizePad))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	s := new(memorySink)
+	a := NewSinkAssembler(s, 5)
+	if err := a.Add(p); err != nil {
+		t.Fatal("expected to add part: " + err.Error())
+	}
+	r := a.Ranges()[0]
+	if r.Length != 5 || r.CRC32 != crc32.ChecksumIEEE(s.buf.Bytes()) {
+		t.Errorf("expected the range to describe the data written got %+v", r)
+	}
+}
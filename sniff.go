@@ -0,0 +1,87 @@
+package yenc
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ContentType is the kind of file detected from the decoded data
+type ContentType string
+
+const (
+	TypeUnknown ContentType = ""
+	TypeRAR     ContentType = "rar"
+	Type7z      ContentType = "7z"
+	TypeZip     ContentType = "zip"
+	TypeJPEG    ContentType = "jpeg"
+	TypePAR2    ContentType = "par2"
+)
+
+// how many leading bytes are kept for sniffing
+const sniffLen = 16
+
+// magic bytes found at the start of each type
+var magics = []struct {
+	magic []byte
+	typ   ContentType
+}{
+	{[]byte("Rar!\x1a\x07"), TypeRAR},
+	{[]byte("7z\xbc\xaf\x27\x1c"), Type7z},
+	{[]byte("PK\x03\x04"), TypeZip},
+	{[]byte("PK\x05\x06"), TypeZip},
+	{[]byte("PK\x07\x08"), TypeZip},
+	{[]byte("\xff\xd8\xff"), TypeJPEG},
+	{[]byte("PAR2\x00PKT"), TypePAR2},
+}
+
+// DetectType sniffs the magic bytes at the start of b, which should
+// be the first bytes of a file
+func DetectType(b []byte) ContentType {
+	for _, m := range magics {
+		if bytes.HasPrefix(b, m.magic) {
+			return m.typ
+		}
+	}
+	return TypeUnknown
+}
+
+// extensions that fit each type, the first is the canonical one
+var typeExts = map[ContentType][]string{
+	TypeRAR:  {".rar", ".cbr"},
+	Type7z:   {".7z", ".cb7"},
+	TypeZip:  {".zip", ".cbz", ".jar", ".apk", ".epub", ".docx", ".xlsx", ".pptx", ".odt"},
+	TypeJPEG: {".jpg", ".jpeg", ".jpe", ".jfif"},
+	TypePAR2: {".par2"},
+}
+
+// numbered volumes (.r00, .z01, .001) start with an archive's magic too
+var volumeExt = regexp.MustCompile(`^\.([rsz]\d\d|\d{3})$`)
+
+// Ext returns the usual file extension for the type, or "" if unknown
+func (t ContentType) Ext() string {
+	if exts := typeExts[t]; len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// fixExtension returns name with its extension replaced by the one
+// matching t, or name unchanged if it already fits
+func fixExtension(name string, t ContentType) string {
+	exts := typeExts[t]
+	if len(exts) == 0 {
+		return name
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return name
+		}
+	}
+	if t != TypeJPEG && t != TypePAR2 && volumeExt.MatchString(ext) {
+		return name
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + exts[0]
+}
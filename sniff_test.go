@@ -0,0 +1,58 @@
+package yenc
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDetectType(t *testing.T) {
+	tests := map[string]ContentType{
+		"Rar!\x1a\x07\x00\xcf":     TypeRAR,
+		"Rar!\x1a\x07\x01\x00":     TypeRAR,
+		"7z\xbc\xaf\x27\x1c\x00":   Type7z,
+		"PK\x03\x04\x14\x00":       TypeZip,
+		"PAR2\x00PKT\x5c\x00\x00":  TypePAR2,
+		"\xff\xd8\xff\xe0\x00\x10": TypeJPEG,
+		"hello":                    TypeUnknown,
+	}
+	for in, expected := range tests {
+		if typ := DetectType([]byte(in)); typ != expected {
+			t.Errorf("expected %q for %q got %q", expected, in, typ)
+		}
+	}
+}
+
+func TestDecodeDetectedType(t *testing.T) {
+	f, err := os.Open("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	part, err := Decode(f)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if part.DetectedType != TypeJPEG {
+		t.Errorf("expected detected type jpeg got %q", part.DetectedType)
+	}
+}
+
+func TestFixExtension(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      ContentType
+		expected string
+	}{
+		{"foo.bin", TypeRAR, "foo.rar"},
+		{"a8f7d6e5c4", TypeRAR, "a8f7d6e5c4.rar"},
+		{"foo.r00", TypeRAR, "foo.r00"},
+		{"foo.part01.rar", TypeRAR, "foo.part01.rar"},
+		{"foo.7z.001", Type7z, "foo.7z.001"},
+		{"photo.JPG", TypeJPEG, "photo.JPG"},
+		{"foo.bin", TypeUnknown, "foo.bin"},
+	}
+	for _, test := range tests {
+		if name := fixExtension(test.name, test.typ); name != test.expected {
+			t.Errorf("expected %s for %s got %s", test.expected, test.name, name)
+		}
+	}
+}
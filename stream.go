@@ -0,0 +1,128 @@
+package yenc
+
+import "io"
+
+// StreamDecoder decodes yenc on the fly, see NewDecoder
+type StreamDecoder struct {
+	d *decoder
+	// whether we are inside a part body, and its span when tracing
+	inBody bool
+	span   Span
+	// decoded bytes not yet read
+	pending []byte
+	err     error
+}
+
+// NewDecoder returns a reader that decodes the yenc data read from r.
+// The payloads of all parts in the stream are returned one after the
+// other without buffering whole parts in memory; each part's size and
+// crc are checked when its trailer is reached and a failure is returned
+// as an error from Read.
+func NewDecoder(r io.Reader, opts ...Option) *StreamDecoder {
+	return &StreamDecoder{d: newDecoder(r, opts...)}
+}
+
+// NewReader is NewDecoder for callers that only want an io.Reader, in
+// the style of gzip.NewReader
+func NewReader(r io.Reader, opts ...Option) io.Reader {
+	return NewDecoder(r, opts...)
+}
+
+// Reset discards the decoder's state and has it decode r from the
+// start with the same options, reusing its buffers and hash state so a
+// long running downloader can decode article after article without
+// allocating a decoder for each.
+func (s *StreamDecoder) Reset(r io.Reader) {
+	s.d.reset(r)
+	*s = StreamDecoder{d: s.d}
+}
+
+func (s *StreamDecoder) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.pending, s.err = s.next()
+		if s.err != nil {
+			s.d.clearDeadline()
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// DecodeN writes up to n decoded bytes to w and stops, keeping its
+// place so a later call carries on where this one finished. Input is
+// only read a line at a time as output is needed, so many streams can
+// share a process by each being given a budget in turn. It returns
+// io.EOF once the stream is exhausted.
+func (s *StreamDecoder) DecodeN(w io.Writer, n int64) (int64, error) {
+	var written int64
+	for written < n {
+		for len(s.pending) == 0 {
+			if s.err != nil {
+				return written, s.err
+			}
+			s.pending, s.err = s.next()
+			if s.err != nil {
+				s.d.clearDeadline()
+			}
+		}
+		b := s.pending[:min(int64(len(s.pending)), n-written)]
+		m, err := w.Write(b)
+		written += int64(m)
+		s.pending = s.pending[m:]
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// decode the next line of output
+func (s *StreamDecoder) next() ([]byte, error) {
+	d := s.d
+	if !s.inBody {
+		d.part = new(Part)
+		if err := d.readHeaders(); err != nil {
+			if err == io.EOF {
+				if err := d.finish(); err != nil {
+					return nil, err
+				}
+			}
+			return nil, err
+		}
+		if !d.wanted() {
+			return nil, unexpected(d.skipBody())
+		}
+		d.startBody()
+		s.inBody = true
+		s.span = d.partSpan("yenc.body")
+	}
+	b, done, err := d.readBodyLine()
+	if err != nil {
+		err = unexpected(err)
+		s.span.End(err)
+		return nil, err
+	}
+	if done {
+		s.inBody = false
+		s.span.SetAttribute(attrBytes, d.part.decoded)
+		s.span.End(nil)
+		d.parts = append(d.parts, d.part)
+		span := d.partSpan("yenc.validate")
+		err := d.checkPart()
+		span.End(err)
+		return nil, err
+	}
+	return b, nil
+}
+
+// running out of input mid part is not a clean end
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
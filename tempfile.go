@@ -0,0 +1,132 @@
+package yenc
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// files are assembled under a temporary name and only renamed into
+// place once complete, so a failed or crashed run never leaves a
+// half written file under the real name
+const (
+	tempPrefix = ".yenc-"
+	tempSuffix = ".tmp"
+)
+
+// tempFile is a file being assembled
+type tempFile struct {
+	*os.File
+	done bool
+}
+
+func createTemp(dir string) (*tempFile, error) {
+	f, err := os.CreateTemp(dir, tempPrefix+"*"+tempSuffix)
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp makes files only the owner can read
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	t := &tempFile{File: f}
+	// a backstop for files that are dropped without commit or discard
+	runtime.SetFinalizer(t, (*tempFile).discard)
+	return t, nil
+}
+
+// commit closes the file and moves it to path. When the file is on
+// another volume it is copied next to path first, so the final rename
+// is still atomic.
+func (t *tempFile) commit(path string) error {
+	defer t.discard()
+	if err := t.Close(); err != nil {
+		return err
+	}
+	err := os.Rename(t.Name(), path)
+	if err == nil {
+		t.done = true
+		return nil
+	}
+	if filepath.Dir(t.Name()) == filepath.Dir(path) {
+		// same volume, copying won't help
+		return err
+	}
+	return t.copyTo(path)
+}
+
+// copy the file to a temp file beside path and commit that
+func (t *tempFile) copyTo(path string) error {
+	src, err := os.Open(t.Name())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := createTemp(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dst.discard()
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.commit(path)
+}
+
+// discard closes and removes the file unless it was committed, so it
+// can be deferred to clean up on errors and panics
+func (t *tempFile) discard() {
+	runtime.SetFinalizer(t, nil)
+	if t.done {
+		return
+	}
+	t.done = true
+	t.Close()
+	os.Remove(t.Name())
+}
+
+// TempFiles lists the temporary files in dir left behind by extractions
+// that crashed before they could clean up. Files belonging to a running
+// extraction are listed too.
+func TempFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, tempPrefix) && strings.HasSuffix(name, tempSuffix) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths, nil
+}
+
+// PurgeTempFiles removes the temporary files in dir that have not been
+// written to for at least age, returning the paths removed. A generous
+// age avoids removing files an extraction is still writing.
+func PurgeTempFiles(dir string, age time.Duration) ([]string, error) {
+	paths, err := TempFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	cutoff := time.Now().Add(-age)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
@@ -0,0 +1,90 @@
+package yenc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExtractLeavesNoTempFiles(t *testing.T) {
+	e := &Extractor{Dir: t.TempDir()}
+	if _, err := e.Extract(bytes.NewReader(extractFixture(t))); err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if paths, _ := TempFiles(e.Dir); len(paths) != 0 {
+		t.Errorf("expected no temp files got %v", paths)
+	}
+	if info, err := os.Stat(filepath.Join(e.Dir, "testfile.txt")); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("expected extracted file with mode 0644 (%v)", err)
+	}
+}
+
+func TestTempFileDiscard(t *testing.T) {
+	dir := t.TempDir()
+	f, err := createTemp(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() { recover() }()
+		defer f.discard()
+		panic("extraction blew up")
+	}()
+	if paths, _ := TempFiles(dir); len(paths) != 0 {
+		t.Errorf("expected temp file to be removed on panic got %v", paths)
+	}
+}
+
+func TestPurgeTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	old, _ := createTemp(dir)
+	fresh, _ := createTemp(dir)
+	defer fresh.discard()
+	// the old one is left behind by a crashed run
+	old.Close()
+	long := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(old.Name(), long, long)
+	paths, err := TempFiles(dir)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("expected 2 temp files got %v (%v)", paths, err)
+	}
+	removed, err := PurgeTempFiles(dir, time.Hour)
+	if err != nil || len(removed) != 1 || removed[0] != old.Name() {
+		t.Errorf("expected only the old file to be purged got %v (%v)", removed, err)
+	}
+}
+
+func TestExtractWithTempDir(t *testing.T) {
+	e := &Extractor{Dir: t.TempDir(), TempDir: t.TempDir()}
+	files, err := e.Extract(bytes.NewReader(extractFixture(t)))
+	if err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	if files[0].Path != filepath.Join(e.Dir, "testfile.txt") || !exists(files[0].Path) {
+		t.Errorf("expected file to be moved into Dir got %q", files[0].Path)
+	}
+	for _, dir := range []string{e.Dir, e.TempDir} {
+		if paths, _ := TempFiles(dir); len(paths) != 0 {
+			t.Errorf("expected no temp files left in %s got %v", dir, paths)
+		}
+	}
+}
+
+func TestTempFileCopyAcrossDirs(t *testing.T) {
+	// the copy used when a rename crosses volumes
+	src, _ := createTemp(t.TempDir())
+	src.WriteString("abc")
+	src.Close()
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if err := src.copyTo(path); err != nil {
+		t.Fatal("expected to copy: " + err.Error())
+	}
+	if b, _ := os.ReadFile(path); string(b) != "abc" {
+		t.Errorf("expected copied data got %q", b)
+	}
+	if paths, _ := TempFiles(filepath.Dir(path)); len(paths) != 0 {
+		t.Errorf("expected no temp files beside the copy got %v", paths)
+	}
+}
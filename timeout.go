@@ -0,0 +1,74 @@
+package yenc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// TimeoutError is returned when decoding runs past the limit set
+// WithTimeout
+type TimeoutError struct {
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("yenc: decode took longer than %v", e.Limit)
+}
+
+// Timeout reports true, as net.Error does for timeouts
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// WithTimeout stops decoding with a *TimeoutError once limit has passed
+// since the decoder was created, so an input trickling in bytes can't
+// hold it forever. The limit is checked between reads; inputs with a
+// SetReadDeadline method, such as a net.Conn, also have their reads
+// cut short, their deadline being cleared again when the decode ends.
+func WithTimeout(limit time.Duration) Option {
+	return func(d *decoder) {
+		d.timeout = limit
+	}
+}
+
+// deadlineReader fails reads once its deadline has passed
+type deadlineReader struct {
+	r        io.Reader
+	limit    time.Duration
+	deadline time.Time
+}
+
+// inputs such as a net.Conn whose reads can be cut short
+type readDeadliner interface {
+	SetReadDeadline(time.Time) error
+}
+
+func newDeadlineReader(r io.Reader, limit time.Duration) *deadlineReader {
+	dr := &deadlineReader{r: r, limit: limit, deadline: time.Now().Add(limit)}
+	if conn, ok := r.(readDeadliner); ok {
+		conn.SetReadDeadline(dr.deadline)
+	}
+	return dr
+}
+
+// clear the deadline set on the input, which outlives the decode. The
+// deadline it had before can't be read back, so none is left.
+func (dr *deadlineReader) clear() {
+	if conn, ok := dr.r.(readDeadliner); ok {
+		conn.SetReadDeadline(time.Time{})
+	}
+}
+
+func (dr *deadlineReader) Read(p []byte) (int, error) {
+	if !time.Now().Before(dr.deadline) {
+		return 0, &TimeoutError{dr.limit}
+	}
+	n, err := dr.r.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = &TimeoutError{dr.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,58 @@
+package yenc
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// hands out one byte per read, slowly
+type trickleReader struct {
+	data  []byte
+	delay time.Duration
+}
+
+func (r *trickleReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	if len(r.data) == 0 {
+		return 0, nil
+	}
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
+
+func TestDecodeWithTimeout(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	_, err = Decode(&trickleReader{raw, time.Millisecond}, WithTimeout(20*time.Millisecond))
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || !timeout.Timeout() {
+		t.Errorf("expected timeout error got %v", err)
+	}
+	if _, err := Decode(bytes.NewReader(raw), WithTimeout(time.Minute)); err != nil {
+		t.Errorf("expected to decode in time: %v", err)
+	}
+}
+
+func TestDecodeWithTimeoutConn(t *testing.T) {
+	// a connection that sends nothing at all
+	client, server := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	_, err := Decode(client, WithTimeout(20*time.Millisecond))
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) {
+		t.Errorf("expected timeout error got %v", err)
+	}
+	// the connection is usable again once the decode has returned
+	go server.Write([]byte("x"))
+	if _, err := client.Read(make([]byte, 1)); err != nil {
+		t.Errorf("expected the deadline to be cleared got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package yenc
+
+// Tracer starts a span for each stage of decoding, so the time spent
+// can be attributed inside a larger trace. The stages are:
+//
+//	yenc.header    reading a part's =ybegin and =ypart lines
+//	yenc.body      decoding a part's body
+//	yenc.validate  checking a part's size and crc, or a whole file's crc
+//	yenc.assemble  an Extractor writing out a file
+//
+// The shape follows OpenTelemetry's Tracer and Span closely enough that
+// a few lines of adapter can forward to them, with the adapter holding
+// the parent context, without this package depending on it.
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span is one traced stage
+type Span interface {
+	// SetAttribute records a detail of the stage, value is an int64
+	// or a string
+	SetAttribute(key string, value interface{})
+	// End finishes the span, err is why the stage failed if it did
+	End(err error)
+}
+
+// span attribute keys
+const (
+	attrName  = "yenc.name"
+	attrPart  = "yenc.part"
+	attrBytes = "yenc.bytes"
+)
+
+// WithTracer traces each stage of the decode with t
+func WithTracer(t Tracer) Option {
+	return func(d *decoder) {
+		d.tracer = t
+	}
+}
+
+// start a span, which does nothing when there is no tracer
+func startSpan(t Tracer, name string) Span {
+	if t == nil {
+		return noSpan{}
+	}
+	return t.Start(name)
+}
+
+type noSpan struct{}
+
+func (noSpan) SetAttribute(string, interface{}) {}
+func (noSpan) End(error)                        {}
+
+// the tracer set by opts, for work done outside a decoder
+func tracerOf(opts []Option) Tracer {
+	d := new(decoder)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d.tracer
+}
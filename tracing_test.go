@@ -0,0 +1,79 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// records spans as "name key=value... [error]"
+type recordingTracer struct {
+	spans []string
+}
+
+type recordingSpan struct {
+	t     *recordingTracer
+	entry string
+}
+
+func (t *recordingTracer) Start(name string) Span {
+	return &recordingSpan{t: t, entry: name}
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.entry += fmt.Sprintf(" %s=%v", key, value)
+}
+
+func (s *recordingSpan) End(err error) {
+	if err != nil {
+		s.entry += " error"
+	}
+	s.t.spans = append(s.t.spans, s.entry)
+}
+
+func TestTracerSpans(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "t.bin", 3)
+	e.Write([]byte("abc"))
+	e.Close()
+	tr := new(recordingTracer)
+	if _, err := Decode(&buf, WithTracer(tr)); err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	expected := []string{
+		"yenc.header",
+		"yenc.body yenc.name=t.bin yenc.part=0 yenc.bytes=3",
+		"yenc.validate yenc.name=t.bin yenc.part=0",
+		"yenc.header",
+		"yenc.validate yenc.name=t.bin",
+	}
+	if got := strings.Join(tr.spans, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("unexpected spans:\n%s", got)
+	}
+}
+
+func TestTracerStreamFailure(t *testing.T) {
+	good := extractFixture(t)
+	tr := new(recordingTracer)
+	bad := bytes.Replace(good, []byte("crc32=ded"), []byte("crc32=eed"), 1)
+	if _, err := io.Copy(io.Discard, NewDecoder(bytes.NewReader(bad), WithTracer(tr))); err == nil {
+		t.Fatal("expected crc failure")
+	}
+	if n := len(tr.spans); n != 3 || !strings.HasSuffix(tr.spans[n-1], " error") || !strings.HasPrefix(tr.spans[n-1], "yenc.validate") {
+		t.Errorf("expected the validate span to fail, got %q", tr.spans)
+	}
+}
+
+func TestTracerExtract(t *testing.T) {
+	tr := new(recordingTracer)
+	e := &Extractor{Dir: t.TempDir(), Options: []Option{WithTracer(tr)}}
+	if _, err := e.Extract(bytes.NewReader(extractFixture(t))); err != nil {
+		t.Fatal("expected to extract: " + err.Error())
+	}
+	last := tr.spans[len(tr.spans)-1]
+	if !strings.HasPrefix(last, "yenc.assemble ") || !strings.Contains(last, "yenc.bytes=584") {
+		t.Errorf("expected an assemble span last, got %q", tr.spans)
+	}
+}
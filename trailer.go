@@ -0,0 +1,120 @@
+package yenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// how far back from the end we look for =yend, the window
+// doubles on each miss until it reaches the limit
+const (
+	peekWindow = 4 << 10
+	peekLimit  = 64 << 10
+)
+
+// Trailer holds the values found on a =yend line
+type Trailer struct {
+	// part number, zero when not given
+	Part int
+	// decoded size of the part
+	Size int64
+	// crc of this part and of the whole file, zero when not given
+	PartCRC32, CRC32 uint32
+	// every keyword and value on the line, including those not
+	// understood
+	Headers map[string]string
+	// whether size= was given
+	hasSize bool
+}
+
+// parse the keywords from a =yend line
+func parseTrailerLine(line string) *Trailer {
+	kw := parseKeywords(line)
+	t := &Trailer{Headers: kw}
+	if size, ok := kw["size"]; ok {
+		t.Size, _ = strconv.ParseInt(size, 10, 64)
+		t.hasSize = true
+	}
+	t.Part, _ = strconv.Atoi(kw["part"])
+	if crc, err := strconv.ParseUint(kw["pcrc32"], 16, 64); err == nil {
+		t.PartCRC32 = uint32(crc)
+	}
+	if crc, err := strconv.ParseUint(kw["crc32"], 16, 64); err == nil {
+		t.CRC32 = uint32(crc)
+	}
+	return t
+}
+
+// PeekTrailer locates and parses the last =yend line in r without
+// decoding the body. The read position of r is restored afterwards.
+func PeekTrailer(r io.ReadSeeker) (*Trailer, error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	t, err := PeekTrailerAt(seekReaderAt{r}, size)
+	if _, serr := r.Seek(pos, io.SeekStart); serr != nil && err == nil {
+		err = serr
+	}
+	return t, err
+}
+
+// PeekTrailerAt is like PeekTrailer but scans the tail of the
+// first size bytes of r.
+func PeekTrailerAt(r io.ReaderAt, size int64) (*Trailer, error) {
+	line, _, err := findTrailerLine(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrailerLine(string(line)), nil
+}
+
+// scan backwards from size for a line starting with =yend, returning
+// the line and the offset it starts at
+func findTrailerLine(r io.ReaderAt, size int64) ([]byte, int64, error) {
+	for n := int64(peekWindow); ; n *= 2 {
+		off := size - n
+		if off < 0 {
+			off = 0
+		}
+		buf := make([]byte, size-off)
+		if _, err := r.ReadAt(buf, off); err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		// the marker must start a line, unless we are at the start of input
+		i := bytes.LastIndex(buf, []byte("\n=yend"))
+		if i > -1 {
+			i++
+		} else if off == 0 && bytes.HasPrefix(buf, []byte("=yend")) {
+			i = 0
+		}
+		if i > -1 {
+			line := buf[i:]
+			if j := bytes.IndexByte(line, '\n'); j > -1 {
+				line = line[:j]
+			}
+			return bytes.TrimRight(line, "\r\n"), off + int64(i), nil
+		}
+		if off == 0 || n >= peekLimit {
+			return nil, 0, fmt.Errorf("yenc: no =yend trailer found in last %d bytes", size-off)
+		}
+	}
+}
+
+// adapts a ReadSeeker for reading at offsets
+type seekReaderAt struct {
+	io.ReadSeeker
+}
+
+func (s seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := s.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(s.ReadSeeker, p)
+}
@@ -0,0 +1,34 @@
+package yenc
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPeekTrailer(t *testing.T) {
+	f, err := os.Open("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	defer f.Close()
+	tr, err := PeekTrailer(f)
+	if err != nil {
+		t.Fatal("expected to find trailer: " + err.Error())
+	}
+	if tr.Part != 1 || tr.Size != 11250 || tr.PartCRC32 != 0xbfae5c0b || tr.Headers["pcrc32"] != "bfae5c0b" {
+		t.Errorf("unexpected trailer %+v", tr)
+	}
+	// position should be left untouched
+	if pos, _ := f.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Errorf("expected read position 0 got %d", pos)
+	}
+}
+
+func TestPeekTrailerMissing(t *testing.T) {
+	r := strings.NewReader("=ybegin line=128 size=3 name=x\r\nabc\r\n")
+	if _, err := PeekTrailer(r); err == nil {
+		t.Errorf("expected error for input without =yend")
+	}
+}
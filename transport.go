@@ -0,0 +1,66 @@
+package yenc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// longest line, without CRLF, that mail and news transports must pass
+const maxTransportLine = 998
+
+// CheckTransport scans encoded output for bytes and sequences that
+// gateways which are not fully 8 bit clean are known to mangle: NUL,
+// CR not followed by LF, LF without CR, lines starting with an
+// unstuffed dot, trailing whitespace and lines over 998 bytes. Each
+// problem is reported once per line. An error is only returned if
+// reading fails.
+func CheckTransport(r io.Reader) (*Report, error) {
+	report := new(Report)
+	buf := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := buf.ReadBytes('\n')
+		if len(line) > 0 {
+			checkTransportLine(report, n, line)
+		}
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+}
+
+func checkTransportLine(report *Report, n int, line []byte) {
+	issue := func(format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{n, fmt.Sprintf(format, args...)})
+	}
+	if bytes.HasPrefix(line, []byte("=ybegin")) {
+		report.Parts++
+	}
+	body := line
+	switch {
+	case bytes.HasSuffix(line, []byte("\r\n")):
+		body = line[:len(line)-2]
+	case bytes.HasSuffix(line, []byte("\n")):
+		body = line[:len(line)-1]
+		issue("LF without CR")
+	}
+	if i := bytes.IndexByte(body, 0); i > -1 {
+		issue("NUL at column %d", i+1)
+	}
+	if i := bytes.IndexByte(body, '\r'); i > -1 {
+		issue("bare CR at column %d", i+1)
+	}
+	if len(body) > 0 && body[0] == '.' {
+		issue("line starts with an unstuffed dot")
+	}
+	if len(body) > 0 && (body[len(body)-1] == ' ' || body[len(body)-1] == '\t') {
+		issue("trailing whitespace")
+	}
+	if len(body) > maxTransportLine {
+		issue("line of %d bytes longer than %d", len(body), maxTransportLine)
+	}
+}
@@ -0,0 +1,37 @@
+package yenc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCheckTransportEncoderOutput(t *testing.T) {
+	// every byte value, in every column
+	data := encodeTestData(50000)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, "data.bin", int64(len(data)))
+	e.Write(data)
+	e.Close()
+	report, err := CheckTransport(&buf)
+	if err != nil || !report.Valid() || report.Parts != 1 {
+		t.Errorf("expected encoder output to be transport safe got %v (%v)", report.Issues, err)
+	}
+}
+
+func TestCheckTransport(t *testing.T) {
+	tests := map[string]string{
+		"ab\x00c\r\n":                     "NUL at column 3",
+		"ab\rc\r\n":                       "bare CR at column 3",
+		"abc\n":                           "LF without CR",
+		".abc\r\n":                        "unstuffed dot",
+		"abc \r\n":                        "trailing whitespace",
+		strings.Repeat("a", 999) + "\r\n": "longer than 998",
+	}
+	for input, expected := range tests {
+		report, err := CheckTransport(strings.NewReader(input))
+		if err != nil || len(report.Issues) != 1 || !strings.Contains(report.Issues[0].Msg, expected) {
+			t.Errorf("%q: expected %q got %v (%v)", input, expected, report.Issues, err)
+		}
+	}
+}
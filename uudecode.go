@@ -0,0 +1,85 @@
+package yenc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeUU finds "begin <mode> <name>" ... "end" blocks of uuencoded
+// data in r and returns each as a single part. Text around the blocks
+// is skipped.
+func decodeUU(r io.Reader) ([]*Part, error) {
+	var parts []*Part
+	var p *Part
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if p == nil {
+			// begin 644 name.ext
+			fields := strings.SplitN(line, " ", 3)
+			if len(fields) == 3 && fields[0] == "begin" && isOctal(fields[1]) {
+				name := strings.TrimSpace(fields[2])
+				p = &Part{Name: name, RawName: name}
+			}
+			continue
+		}
+		if line == "end" {
+			p.Begin, p.End = 1, int64(len(p.Body))
+			p.Size, p.HeaderSize = p.End, p.End
+			p.DetectedType = DetectType(p.Body)
+			parts = append(parts, p)
+			p = nil
+			continue
+		}
+		b, err := uuDecodeLine(line)
+		if err != nil {
+			return parts, fmt.Errorf("yenc: %s: %w", p.Name, err)
+		}
+		p.Body = append(p.Body, b...)
+	}
+	if err := scanner.Err(); err != nil {
+		return parts, err
+	}
+	if p != nil {
+		return parts, fmt.Errorf("yenc: %s: uuencoded data has no end line", p.Name)
+	}
+	return parts, nil
+}
+
+func isOctal(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '7' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// decode one line, the first char gives the decoded length and each
+// following group of four chars carries three bytes
+func uuDecodeLine(line string) ([]byte, error) {
+	if line == "" {
+		return nil, nil
+	}
+	n := int(line[0]-' ') & 63
+	// some encoders drop trailing spaces, which are zero
+	data := line[1:]
+	if need := (n + 2) / 3 * 4; len(data) < need {
+		data += strings.Repeat(" ", need-len(data))
+	}
+	out := make([]byte, 0, n+2)
+	for i := 0; len(out) < n; i += 4 {
+		var c [4]byte
+		for j := range c {
+			c[j] = (data[i+j] - ' ') & 63
+			if data[i+j] < ' ' || data[i+j] > '`' {
+				return nil, fmt.Errorf("invalid uuencoded char %q", data[i+j])
+			}
+		}
+		out = append(out, c[0]<<2|c[1]>>4, c[1]<<4|c[2]>>2, c[2]<<6|c[3])
+	}
+	return out[:n], nil
+}
@@ -0,0 +1,296 @@
+package yenc
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SpecVersion is the version of the yenc spec Validate checks against,
+// also the first of Features' SpecVersions
+const SpecVersion = "1.3"
+
+// Issue is a single way an encoded stream breaks the SpecVersion grammar
+type Issue struct {
+	// line number in the input, from 1
+	Line int
+	Msg  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s", i.Line, i.Msg)
+}
+
+// Report lists the issues found by Validate
+type Report struct {
+	// number of =ybegin blocks seen
+	Parts  int
+	Issues []Issue
+}
+
+// Valid reports whether no issues were found
+func (r *Report) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+// a validator tracks the state of the block being checked
+type validator struct {
+	report *Report
+	line   int
+	// header values of the current block
+	inBlock   bool
+	multipart bool
+	part      int
+	cols      int64
+	size      int64
+	begin     int64
+	end       int64
+	// body state
+	awaitingPart bool
+	decoded      int64
+	crc          uint32
+	// lines are checked for length one behind, as the last may be short
+	lastLen int
+}
+
+// Validate checks an encoded stream against the SpecVersion grammar and
+// semantics without keeping any output: required keywords, numeric
+// values, line lengths, unescaped critical chars, =ypart ranges and
+// that trailer sizes and crcs match the data. An error is only
+// returned if reading fails.
+func Validate(r io.Reader) (*Report, error) {
+	v := &validator{report: new(Report)}
+	buf := bufio.NewReader(r)
+	for {
+		s, err := buf.ReadString('\n')
+		if len(s) > 0 {
+			v.line++
+			v.check(s)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return v.report, err
+		}
+	}
+	if v.inBlock {
+		v.issue("missing =yend before end of input")
+	}
+	if v.report.Parts == 0 {
+		v.issue("no =ybegin header found")
+	}
+	return v.report, nil
+}
+
+func (v *validator) issue(format string, args ...interface{}) {
+	v.report.Issues = append(v.report.Issues, Issue{v.line, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(s string) {
+	if !strings.HasSuffix(s, "\r\n") {
+		if strings.HasSuffix(s, "\n") {
+			v.issue("line ends in LF without CR")
+		} else {
+			v.issue("missing line ending")
+		}
+	}
+	line := strings.TrimRight(s, "\r\n")
+	if header, trimmed := trimLinePreamble(line); trimmed && strings.HasPrefix(header, "=ybegin") {
+		v.issue("BOM or whitespace before =ybegin")
+		line = header
+	} else if i := strings.Index(line, "=ybegin "); i > 0 && !v.inBlock {
+		v.issue("=ybegin after junk on the same line")
+		line = line[i:]
+	}
+	switch {
+	case strings.HasPrefix(line, "=ybegin"):
+		if v.inBlock {
+			v.issue("=ybegin before =yend of previous block")
+		}
+		v.header(line)
+	case !v.inBlock:
+		// anything between blocks is ignored
+	case v.awaitingPart:
+		v.partHeader(line)
+	case strings.HasPrefix(line, "=yend"):
+		v.trailer(line)
+	default:
+		v.body(line)
+	}
+}
+
+// split keyword=value pairs as the decoder does, noting what the spec
+// doesn't allow but parseKeywords lets pass
+func (v *validator) keywords(line string, prefix string) map[string]string {
+	rest := line[len(prefix):]
+	if !strings.HasPrefix(rest, " ") {
+		v.issue("%s not followed by a space", prefix)
+	}
+	fields := rest
+	if i := strings.Index(rest, " name="); i > -1 {
+		fields = rest[:i]
+	}
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(strings.TrimSpace(fields), " ") {
+		key, _, ok := strings.Cut(field, "=")
+		switch {
+		case field == "":
+			v.issue("repeated space between keywords")
+		case strings.ContainsRune(field, '\t'):
+			v.issue("tab between keywords")
+		case !ok:
+			v.issue("malformed keyword %q", field)
+		case seen[key]:
+			v.issue("repeated keyword %s", key)
+		}
+		seen[key] = true
+	}
+	return parseKeywords(rest)
+}
+
+// parse a required decimal keyword
+func (v *validator) number(kw map[string]string, key, where string) int64 {
+	s, ok := kw[key]
+	if !ok {
+		v.issue("%s missing %s=", where, key)
+		return 0
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		v.issue("%s has invalid %s=%s", where, key, s)
+	}
+	return n
+}
+
+func (v *validator) header(line string) {
+	*v = validator{report: v.report, line: v.line, inBlock: true}
+	v.report.Parts++
+	kw := v.keywords(line, "=ybegin")
+	if name, ok := kw["name"]; !ok || strings.TrimSpace(name) == "" {
+		v.issue("=ybegin missing name=")
+	}
+	v.cols = v.number(kw, "line", "=ybegin")
+	v.size = v.number(kw, "size", "=ybegin")
+	if _, ok := kw["part"]; ok {
+		v.multipart = true
+		v.awaitingPart = true
+		v.part = int(v.number(kw, "part", "=ybegin"))
+		if v.part < 1 {
+			v.issue("=ybegin part numbers start at 1")
+		}
+		if _, ok := kw["total"]; ok {
+			if total := v.number(kw, "total", "=ybegin"); int64(v.part) > total {
+				v.issue("=ybegin part %d greater than total %d", v.part, total)
+			}
+		}
+	} else if _, ok := kw["total"]; ok {
+		v.issue("=ybegin has total= without part=")
+	}
+}
+
+func (v *validator) partHeader(line string) {
+	v.awaitingPart = false
+	if !strings.HasPrefix(line, "=ypart") {
+		v.issue("multipart =ybegin not followed by =ypart")
+		v.body(line)
+		return
+	}
+	kw := v.keywords(line, "=ypart")
+	v.begin = v.number(kw, "begin", "=ypart")
+	v.end = v.number(kw, "end", "=ypart")
+	switch {
+	case v.begin < 1:
+		v.issue("=ypart begin must be at least 1")
+	case v.end < v.begin:
+		v.issue("=ypart end %d before begin %d", v.end, v.begin)
+	case v.end > v.size:
+		v.issue("=ypart end %d beyond file size %d", v.end, v.size)
+	}
+}
+
+func (v *validator) body(line string) {
+	// only the last body line may be shorter than line=
+	if v.lastLen > 0 && v.cols > 0 && int64(v.lastLen) < v.cols {
+		v.issue("short line of %d chars before end of body", v.lastLen)
+	}
+	if v.cols > 0 && int64(len(line)) > v.cols+1 {
+		v.issue("line of %d chars longer than line=%d", len(line), v.cols)
+	}
+	v.lastLen = len(line)
+	escaped := false
+	out := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			out = append(out, c-64-42)
+			escaped = false
+		case c == '=':
+			escaped = true
+		case c == 0:
+			v.issue("unescaped NUL at column %d", i+1)
+		case c == '\r':
+			v.issue("unescaped CR at column %d", i+1)
+		default:
+			out = append(out, c-42)
+		}
+	}
+	if escaped {
+		v.issue("escape char at end of line")
+	}
+	v.decoded += int64(len(out))
+	v.crc = crc32.Update(v.crc, crc32.IEEETable, out)
+}
+
+// check an optional hex crc keyword against the data
+func (v *validator) checkCRC(kw map[string]string, key string, expected uint32) {
+	s, ok := kw[key]
+	if !ok {
+		return
+	}
+	crc, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		v.issue("=yend has invalid %s=%s", key, s)
+		return
+	}
+	if len(s) != 8 {
+		v.issue("=yend %s should be 8 hex digits", key)
+	}
+	if uint32(crc) != expected {
+		v.issue("=yend %s=%s does not match data crc %08x", key, s, expected)
+	}
+}
+
+func (v *validator) trailer(line string) {
+	v.inBlock = false
+	kw := v.keywords(line, "=yend")
+	size := v.number(kw, "size", "=yend")
+	if _, ok := kw["size"]; !ok {
+		// reported already, check the rest against what was decoded
+		size = v.decoded
+	}
+	if size != v.decoded {
+		v.issue("=yend size=%d but %d bytes decoded", size, v.decoded)
+	}
+	if !v.multipart {
+		if size != v.size {
+			v.issue("=yend size=%d does not match =ybegin size=%d", size, v.size)
+		}
+		v.checkCRC(kw, "crc32", v.crc)
+		return
+	}
+	if part := v.number(kw, "part", "=yend"); int(part) != v.part {
+		v.issue("=yend part=%d does not match =ybegin part=%d", part, v.part)
+	}
+	if size != v.end-v.begin+1 {
+		v.issue("=yend size=%d does not match =ypart range %d-%d", size, v.begin, v.end)
+	}
+	if _, ok := kw["pcrc32"]; !ok {
+		v.issue("multipart =yend missing pcrc32=")
+	}
+	v.checkCRC(kw, "pcrc32", v.crc)
+}
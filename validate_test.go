@@ -0,0 +1,71 @@
+package yenc
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateEncoderOutput(t *testing.T) {
+	data := encodeTestData(10000)
+	var all bytes.Buffer
+	m := &MultipartEncoder{PartSize: 3000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &all, nil
+	})
+	e := NewEncoder(&all, "single.bin", int64(len(data)))
+	e.Write(data)
+	e.Close()
+	report, err := Validate(&all)
+	if err != nil {
+		t.Fatal("expected to validate: " + err.Error())
+	}
+	if !report.Valid() || report.Parts != 5 {
+		t.Errorf("expected 5 valid parts got %d with issues %v", report.Parts, report.Issues)
+	}
+}
+
+func TestValidateFixtures(t *testing.T) {
+	for _, name := range []string{"singlepart_test.yenc", "multipart_test.yenc"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal("could not open " + name + " for testing")
+		}
+		report, err := Validate(f)
+		f.Close()
+		if err != nil || !report.Valid() {
+			t.Errorf("%s: expected no issues got %v (%v)", name, report.Issues, err)
+		}
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	tests := map[string]string{
+		"=ybegin size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3\r\n":                              "missing line=",
+		"=ybegin line=128 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=4\r\n":                     "but 3 bytes decoded",
+		"=ybegin line=128 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3 crc32=00000000\r\n":      "does not match data crc",
+		"=ybegin line=128 size=3 name=x\r\n\x8b\x8c\x8d=\r\n=yend size=3\r\n":                    "escape char at end of line",
+		"=ybegin line=128 size=3 name=x\r\n\x8b\x8c\x8d\r\n":                                     "missing =yend",
+		"=ybegin part=1 line=128 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3 part=1\r\n":       "not followed by =ypart",
+		"=ybegin part=1 line=128 size=3 name=x\r\n=ypart begin=1 end=3\r\nabc\r\n=yend size=3\n": "LF without CR",
+		"junk=ybegin line=128 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3\r\n":                 "after junk",
+		"\xef\xbb\xbf=ybegin line=128 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3\r\n":         "BOM or whitespace",
+		"=ybegin line=128\tsize=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3\r\n":                    "tab between keywords",
+		"=ybegin line=128 size=3 size=3 name=x\r\n\x8b\x8c\x8d\r\n=yend size=3\r\n":              "repeated keyword size",
+	}
+	for input, expected := range tests {
+		report, err := Validate(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, issue := range report.Issues {
+			found = found || strings.Contains(issue.Msg, expected)
+		}
+		if !found {
+			t.Errorf("expected issue %q for %q got %v", expected, input, report.Issues)
+		}
+	}
+}
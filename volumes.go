@@ -0,0 +1,88 @@
+package yenc
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VolumeGroup is a set of files that make up one multi-volume archive
+type VolumeGroup struct {
+	// name of the set, eg foo.rar or foo.7z
+	Name string
+	// file names in volume order
+	Files []string
+}
+
+var (
+	// foo.part01.rar
+	partVolume = regexp.MustCompile(`(?i)^(.+)\.part(\d+)\.(rar)$`)
+	// foo.r00, foo.s00 (follows r99)
+	oldRarVolume = regexp.MustCompile(`(?i)^(.+)\.([rs])(\d\d)$`)
+	// foo.z01, ends with foo.zip
+	zipVolume = regexp.MustCompile(`(?i)^(.+)\.z(\d\d)$`)
+	// foo.7z.001, foo.zip.001
+	splitVolume = regexp.MustCompile(`(?i)^(.+\.[a-z0-9]+)\.(\d{3})$`)
+)
+
+// work out which set a name belongs to and its position in it
+func volumeKey(name string) (set string, index int) {
+	if m := partVolume.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return m[1] + "." + m[3], n
+	}
+	if m := oldRarVolume.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[3])
+		if strings.EqualFold(m[2], "s") {
+			n += 100
+		}
+		return m[1] + ".rar", n + 1
+	}
+	if m := zipVolume.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return m[1] + ".zip", n
+	}
+	if m := splitVolume.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return m[1], n
+	}
+	// a plain foo.zip is the last volume of a foo.z01 set
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		return name, 1 << 30
+	}
+	return name, 0
+}
+
+// GroupVolumes groups file names that belong to the same multi-volume
+// archive (foo.part01.rar.., foo.rar + foo.r00.., foo.7z.001..) and
+// returns the groups sorted by name with each group's files in volume
+// order. Names that are not part of a set are returned as a group of one.
+func GroupVolumes(names []string) []VolumeGroup {
+	type volume struct {
+		name  string
+		index int
+	}
+	sets := make(map[string][]volume)
+	for _, name := range names {
+		set, index := volumeKey(name)
+		key := strings.ToLower(set)
+		sets[key] = append(sets[key], volume{name, index})
+	}
+	groups := make([]VolumeGroup, 0, len(sets))
+	for _, vols := range sets {
+		sort.SliceStable(vols, func(i, j int) bool {
+			return vols[i].index < vols[j].index
+		})
+		g := VolumeGroup{Files: make([]string, len(vols))}
+		for i, v := range vols {
+			g.Files[i] = v.name
+		}
+		g.Name, _ = volumeKey(vols[0].name)
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
@@ -0,0 +1,26 @@
+package yenc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupVolumes(t *testing.T) {
+	names := []string{
+		"foo.part02.rar", "foo.part10.rar", "foo.part01.rar",
+		"bar.r01", "bar.rar", "bar.r00",
+		"baz.7z.002", "baz.7z.001",
+		"qux.zip", "qux.z01",
+		"readme.nfo",
+	}
+	expected := []VolumeGroup{
+		{"bar.rar", []string{"bar.rar", "bar.r00", "bar.r01"}},
+		{"baz.7z", []string{"baz.7z.001", "baz.7z.002"}},
+		{"foo.rar", []string{"foo.part01.rar", "foo.part02.rar", "foo.part10.rar"}},
+		{"qux.zip", []string{"qux.z01", "qux.zip"}},
+		{"readme.nfo", []string{"readme.nfo"}},
+	}
+	if groups := GroupVolumes(names); !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected groups %v got %v", expected, groups)
+	}
+}
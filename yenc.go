@@ -5,65 +5,132 @@ package yenc
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash"
 	"hash/crc32"
+	"hash/crc64"
 	"io"
+	"maps"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-func parseHeaders(inputBytes []byte) map[string]string {
-	values := make(map[string]string)
-	input := string(inputBytes)
-	// get the filename name off the end
-	ni := strings.Index(input, "name=")
-	if ni > -1 {
-		values["name"] = input[ni:]
-	} else {
-		ni = len(input)
-	}
-	// get other header values
-	for _, header := range strings.Split(input[:ni], " ") {
-		kv := strings.SplitN(strings.TrimSpace(header), "=", 2)
-		if len(kv) < 2 {
-			continue
+// parseKeywords splits the keyword=value pairs of a =ybegin, =ypart or
+// =yend line. Keywords may be separated by any run of spaces and tabs.
+// The spec puts name= last and lets a name hold spaces and = signs, so
+// it takes the rest of the line.
+func parseKeywords(line string) map[string]string {
+	kw := make(map[string]string)
+	line = strings.TrimRight(line, "\r\n")
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return kw
+		}
+		if strings.HasPrefix(line, "name=") {
+			kw["name"] = strings.TrimSpace(line[5:])
+			return kw
+		}
+		field := line
+		if i := strings.IndexAny(line, " \t"); i > -1 {
+			field, line = line[:i], line[i:]
+		} else {
+			line = ""
+		}
+		// the line's own =ybegin splits into an empty keyword
+		if k, v, ok := strings.Cut(field, "="); ok && k != "" {
+			kw[k] = v
 		}
-		values[kv[0]] = kv[1]
 	}
-	// done
-	return values
 }
 
 type Part struct {
+	// yenc version of the headers, 1 or 2 for the =ybegin2 headers of
+	// the draft read WithYEnc2
+	Version int
 	// part num
 	Number int
-	// size from header
-	hsize int64
+	// number of parts from the header, zero if not given
+	Total int
+	// size of the whole file from the header
+	HeaderSize int64
 	// size from part trailer
 	Size int64
 	// file boundarys
 	Begin, End int64
-	// filename from yenc header
+	// filename from yenc header, sanitized when decoding
+	// WithSanitizedNames
 	Name string
-	// line length of part
-	cols int
-	// crc check for this part
-	crc32   uint32
-	crcHash hash.Hash32
+	// filename exactly as the header gave it
+	RawName string
+	// line length from the header
+	Line int
+	// crc32 of this part's data from its trailer, pcrc32 or for single
+	// part data crc32, zero if not given
+	CRC32 uint32
+	// crc32 of the whole file from the trailer, zero if not given
+	FileCRC32 uint32
+	crcHash   hash.Hash32
+	// crc64 (ECMA) of the decoded data, only set when decoding WithCRC64
+	CRC64     uint64
+	crc64Hash hash.Hash64
+	// sha256 of the decoded data, only kept when decoding
+	// WithFingerprints
+	sha256Hash hash.Hash
+	// file type sniffed from the data, only known for the first part
+	DetectedType ContentType
+	// first bytes of the data, for sniffing
+	head []byte
+	// number of bytes decoded
+	decoded int64
+	// counts of escapes and anomalies seen in the encoded data
+	Stats Stats
+	// problems that were not treated as errors, such as crc
+	// mismatches when decoding WithCRCWarnings
+	Warnings []error
+	// the encoded input from =ybegin to =yend exactly as read, only
+	// kept when decoding WithRaw
+	Raw []byte
+	// the =ybegin, =ypart and =yend lines as read, line endings
+	// included, only kept when decoding WithHeaderLines
+	HeaderLine, PartLine, TrailerLine string
+	// every keyword and value on the =ybegin and =ypart lines, and on
+	// the =yend line, including those the decoder doesn't understand
+	Headers, TrailerHeaders map[string]string
 	// the decoded data
 	Body []byte
+	// where Body came from, when decoding WithAllocator
+	alloc Allocator
 }
 
-func (p *Part) validate() error {
+// Release hands the part's body back to the Allocator it came from.
+// Body must not be used afterwards and is set to nil.
+func (p *Part) Release() {
+	if p.alloc != nil && cap(p.Body) > 0 {
+		p.alloc.Put(p.Body[:cap(p.Body)])
+	}
+	p.alloc = nil
+	p.Body = nil
+}
+
+func (p *Part) validateSize() error {
 	// length checks
-	if int64(len(p.Body)) != p.Size {
-		return fmt.Errorf("Body size %d did not match expected size %d", len(p.Body), p.Size)
+	if p.decoded != p.Size {
+		return fmt.Errorf("%w: body size %d did not match expected size %d", ErrSizeMismatch, p.decoded, p.Size)
 	}
+	return nil
+}
+
+func (p *Part) validateCRC() error {
 	// crc check
-	if p.crc32 > 0 {
-		if sum := p.crcHash.Sum32(); sum != p.crc32 {
-			return fmt.Errorf("crc check failed for part %d expected %x got %x", p.Number, p.crc32, sum)
+	if p.CRC32 > 0 {
+		if sum := p.crcHash.Sum32(); sum != p.CRC32 {
+			return fmt.Errorf("%w for part %d expected %x got %x", ErrCRCMismatch, p.Number, p.CRC32, sum)
 		}
 	}
 	return nil
@@ -85,211 +152,987 @@ type decoder struct {
 	crcHash hash.Hash32
 	// are we waiting for an escaped char
 	awaitingSpecial bool
+	// options
+	withCRC64    bool
+	fingerprints bool
+	skipCRC      bool
+	crcWarnings  bool
+	// negative until WithMaxPartSize, see bufferLimit
+	maxPartSize int64
+	// limits on header lines and the number of parts
+	maxHeaderLine int
+	maxParts      int
+	sizePolicy    SizePolicy
+	// what to do with unescaped whitespace ending a line, and with an
+	// escape left open there
+	whitespacePolicy WhitespacePolicy
+	escapePolicy     EscapePolicy
+	withRaw          bool
+	partial          bool
+	sanitizeNames    bool
+	nameCharset      Charset
+	// read the =ybegin2 headers of the yenc 2 draft
+	yenc2 bool
+	// decode without keeping bodies, for Verify
+	discard   bool
+	rawTee    io.Writer
+	nntp      bool
+	withLines bool
+	resync    bool
+	// working out missing =ypart offsets, from where the part before
+	// ended
+	inferOffsets bool
+	lastNumber   int
+	lastEnd      int64
+	lineFuncs    []LineFunc
+	chunkFuncs   []ChunkFunc
+	alloc        Allocator
+	// decides which parts are decoded, the rest are skipped
+	filter  func(p *Part) bool
+	skipped int
+	// limit on the time spent reading input
+	timeout time.Duration
+	// the input's deadline reader, while it has one
+	deadline *deadlineReader
+	// holds lines too long for the read buffer
+	scratch []byte
+	// spans around each stage, when tracing
+	tracer Tracer
+	// timing, when collecting metrics
+	metrics       *Metrics
+	started, mark time.Time
 }
 
 func (d *decoder) validate() error {
 	if d.crc32 > 0 {
 		if sum := d.crcHash.Sum32(); sum != d.crc32 {
-			return fmt.Errorf("crc check failed expected %x got %x", d.crc32, sum)
+			return fmt.Errorf("%w expected %x got %x", ErrCRCMismatch, d.crc32, sum)
 		}
 	}
 	return nil
 }
 
+// a UTF-8 byte order mark, which some editors save files with
+const bom = "\xef\xbb\xbf"
+
+// strip a BOM and whitespace left in front of a line, reporting
+// whether there were any
+func trimLinePreamble(s string) (string, bool) {
+	t := strings.TrimLeft(strings.TrimPrefix(s, bom), " \t")
+	return t, len(t) != len(s)
+}
+
+// the yenc version of a line starting with keyword, such as =ybegin: 1
+// for the keyword itself, 2 for its draft form such as =ybegin2 when
+// yenc2 is set, and 0 for other lines
+func lineVersion[T string | []byte](line T, keyword string, yenc2 bool) int {
+	if len(line) < len(keyword) || string(line[:len(keyword)]) != keyword {
+		return 0
+	}
+	if len(line) > len(keyword) && line[len(keyword)] == '2' {
+		if yenc2 {
+			return 2
+		}
+		return 0
+	}
+	return 1
+}
+
+// where a =ybegin header starts in s after junk, -1 if it doesn't
+func (d *decoder) findHeader(s string) int {
+	i := strings.Index(s, "=ybegin ")
+	if i < 0 && d.yenc2 {
+		i = strings.Index(s, "=ybegin2 ")
+	}
+	return i
+}
+
 func (d *decoder) readHeader() (err error) {
-	var s string
+	var s, line string
+	var n int
 	// find the start of the header
 	for {
-		s, err = d.buf.ReadString('\n')
+		s, n, err = d.readHeaderLine()
 		if err != nil {
 			return err
 		}
-		if len(s) >= 7 && s[:7] == "=ybegin" {
+		var trimmed bool
+		line, trimmed = trimLinePreamble(s)
+		if lineVersion(line, "=ybegin", d.yenc2) > 0 {
+			if trimmed {
+				d.part.Stats.Preamble++
+			}
+			break
+		}
+		if i := d.findHeader(s); d.resync && i > 0 {
+			d.part.Stats.Resyncs++
+			line = s[i:]
 			break
 		}
 	}
-	// split on name= to get name first
-	parts := strings.SplitN(s[7:], "name=", 2)
-	if len(parts) > 1 {
-		d.part.Name = strings.TrimSpace(parts[1])
+	d.part.Version = lineVersion(line, "=ybegin", d.yenc2)
+	if err := d.checkHeaderLine(n); err != nil {
+		return err
 	}
-	// split on sapce for other headers
-	parts = strings.Split(parts[0], " ")
-	for i, _ := range parts {
-		kv := strings.Split(strings.TrimSpace(parts[i]), "=")
-		if len(kv) < 2 {
-			continue
-		}
-		switch kv[0] {
-		case "size":
-			d.part.hsize, _ = strconv.ParseInt(kv[1], 10, 64)
-		case "line":
-			d.part.cols, _ = strconv.Atoi(kv[1])
-		case "part":
-			d.part.Number, _ = strconv.Atoi(kv[1])
-			d.multipart = true
-		case "total":
-			d.total, _ = strconv.Atoi(kv[1])
-		}
+	d.keepRaw([]byte(s))
+	if d.withLines {
+		d.part.HeaderLine = s
+	}
+	kw := parseKeywords(line)
+	d.part.Headers = kw
+	if name, ok := kw["name"]; ok {
+		d.setName(name)
+	}
+	d.part.HeaderSize, _ = strconv.ParseInt(kw["size"], 10, 64)
+	d.part.Line, _ = strconv.Atoi(kw["line"])
+	// each header says whether it is multipart
+	_, d.multipart = kw["part"]
+	if d.multipart {
+		d.part.Number, _ = strconv.Atoi(kw["part"])
+	}
+	if total, ok := kw["total"]; ok {
+		d.total, _ = strconv.Atoi(total)
+		d.part.Total = d.total
 	}
 	return nil
 }
 
 func (d *decoder) readPartHeader() (err error) {
+	if d.inferOffsets {
+		// leave a missing =ypart line to be made up for
+		if b, _ := d.buf.Peek(7); lineVersion(b, "=ypart", d.yenc2) == 0 {
+			return nil
+		}
+	}
 	var s string
+	var n int
 	// find the start of the header
 	for {
-		s, err = d.buf.ReadString('\n')
+		s, n, err = d.readHeaderLine()
 		if err != nil {
 			return err
 		}
-		if len(s) >= 6 && s[:6] == "=ypart" {
+		d.keepRaw([]byte(s))
+		if lineVersion(s, "=ypart", d.yenc2) > 0 {
 			break
 		}
 	}
-	// split on space for headers
-	parts := strings.Split(s[6:], " ")
-	for i, _ := range parts {
-		kv := strings.Split(strings.TrimSpace(parts[i]), "=")
-		if len(kv) < 2 {
-			continue
-		}
-		switch kv[0] {
-		case "begin":
-			d.part.Begin, _ = strconv.ParseInt(kv[1], 10, 64)
-		case "end":
-			d.part.End, _ = strconv.ParseInt(kv[1], 10, 64)
-		}
+	if err := d.checkHeaderLine(n); err != nil {
+		return err
 	}
+	if d.withLines {
+		d.part.PartLine = s
+	}
+	kw := parseKeywords(s)
+	maps.Copy(d.part.Headers, kw)
+	d.part.Begin, _ = strconv.ParseInt(kw["begin"], 10, 64)
+	d.part.End, _ = strconv.ParseInt(kw["end"], 10, 64)
 	return nil
 }
 
 func (d *decoder) parseTrailer(line string) error {
-	// split on space for headers
-	parts := strings.Split(line, " ")
-	for i, _ := range parts {
-		kv := strings.Split(strings.TrimSpace(parts[i]), "=")
-		if len(kv) < 2 {
-			continue
-		}
-		switch kv[0] {
-		case "size":
-			d.part.Size, _ = strconv.ParseInt(kv[1], 10, 64)
-		case "pcrc32":
-			if crc64, err := strconv.ParseUint(kv[1], 16, 64); err == nil {
-				d.part.crc32 = uint32(crc64)
-			}
-		case "crc32":
-			if crc64, err := strconv.ParseUint(kv[1], 16, 64); err == nil {
-				d.crc32 = uint32(crc64)
-			}
-		case "part":
-			partNum, _ := strconv.Atoi(kv[1])
-			if partNum != d.part.Number {
-				return fmt.Errorf("yenc: =yend header out of order expected part %d got %d", d.part.Number, partNum)
-			}
+	t := parseTrailerLine(line)
+	d.part.TrailerHeaders = t.Headers
+	if d.inferOffsets && d.multipart {
+		d.inferRange()
+	}
+	d.part.Size = t.Size
+	if !t.hasSize {
+		d.guessSize()
+	}
+	if t.PartCRC32 > 0 {
+		d.part.CRC32 = t.PartCRC32
+	}
+	if t.CRC32 > 0 {
+		d.crc32 = t.CRC32
+		d.part.FileCRC32 = t.CRC32
+		// a single part carries the file crc as its own
+		if !d.multipart && d.part.CRC32 == 0 {
+			d.part.CRC32 = t.CRC32
 		}
 	}
+	if t.Part > 0 && t.Part != d.part.Number {
+		return fmt.Errorf("yenc: =yend header out of order expected part %d got %d", d.part.Number, t.Part)
+	}
 	return nil
 }
 
+// fill in the range of a part with missing or unusable =ypart offsets
+// from the end of the part before it
+func (d *decoder) inferRange() {
+	p := d.part
+	if p.Begin < 1 || p.End < p.Begin || (p.HeaderSize > 0 && p.End > p.HeaderSize) {
+		switch {
+		case p.Number == 1:
+			p.Begin = 1
+		case p.Number == d.lastNumber+1 && d.lastEnd > 0:
+			p.Begin = d.lastEnd + 1
+		default:
+			p.Begin, p.End = 0, 0
+			p.Warnings = append(p.Warnings, fmt.Errorf("yenc: part %d has no usable =ypart offsets and follows part %d", p.Number, d.lastNumber))
+			d.lastNumber, d.lastEnd = p.Number, 0
+			return
+		}
+		p.End = p.Begin + p.decoded - 1
+		p.Warnings = append(p.Warnings, fmt.Errorf("yenc: part %d has no usable =ypart offsets, inferred %d-%d", p.Number, p.Begin, p.End))
+	}
+	d.lastNumber, d.lastEnd = p.Number, p.End
+}
+
+// some hand rolled posts leave size= off the trailer, so take the size
+// from the headers or failing that from the data, noting that it was
+// guessed
+func (d *decoder) guessSize() {
+	p := d.part
+	switch {
+	case d.multipart && p.Begin > 0 && p.End >= p.Begin:
+		p.Size = p.End - p.Begin + 1
+		p.Warnings = append(p.Warnings, fmt.Errorf("yenc: =yend has no size, using %d from the =ypart range", p.Size))
+		return
+	case !d.multipart && p.HeaderSize > 0:
+		p.Size = p.HeaderSize
+		p.Warnings = append(p.Warnings, fmt.Errorf("yenc: =yend has no size, using %d from the =ybegin header", p.Size))
+		return
+	}
+	p.Size = p.decoded
+	p.Warnings = append(p.Warnings, fmt.Errorf("yenc: =yend has no size, using the %d bytes decoded", p.Size))
+}
+
+// Stats counts escapes and anomalies in the encoded data of a part,
+// which helps tell which server is mangling articles
+type Stats struct {
+	// body lines
+	Lines int
+	// escape sequences
+	Escaped int
+	// lines longer than the header's line length allows
+	LongLines int
+	// lines ending in spaces or tabs that weren't escaped, see
+	// WithWhitespacePolicy
+	TrailingWhitespace int
+	// lines ending in an escape, see WithEscapePolicy
+	LineEndEscapes int
+	// chars an encoder should never emit as they are (NUL, bare CR)
+	// or escapes of chars that never need one
+	Unexpected int
+	// =ybegin lines found after a BOM or whitespace was skipped
+	Preamble int
+	// =ybegin headers found after junk on their line, WithResync
+	Resyncs int
+}
+
+// chars a yenc encoder may legitimately escape
+func escapable(c byte) bool {
+	switch c {
+	case 0, '\n', '\r', '=', ' ', '\t', '.':
+		return true
+	}
+	return false
+}
+
+// lookup tables for decoding a char as it is, and as the second char of
+// an escape, with 1 for the chars that counts as unexpected
+var (
+	plainTable, escapedTable                    [256]byte
+	plainUnexpectedTable, escapeUnexpectedTable [256]int
+)
+
+func init() {
+	for i := range 256 {
+		c := byte(i)
+		plainTable[i] = c - 42
+		escapedTable[i] = c - 42 - 64
+		if c == 0 || c == '\r' {
+			plainUnexpectedTable[i] = 1
+		}
+		if !escapable(c - 64) {
+			escapeUnexpectedTable[i] = 1
+		}
+	}
+}
+
+// decode line in place, returning the shorter slice left once escapes
+// are removed
 func (d *decoder) decode(line []byte) []byte {
+	stats := &d.part.Stats
 	i, j := 0, 0
-	for ; i < len(line); i, j = i+1, j+1 {
-		// escaped chars yenc42+yenc64
-		if d.awaitingSpecial {
-			line[j] = (((line[i] - 42) & 255) - 64) & 255
-			d.awaitingSpecial = false
-			// if escape char - then skip and backtrack j
-		} else if line[i] == '=' {
-			d.awaitingSpecial = true
-			j--
+	// an escape left open at the end of the last line
+	if d.awaitingSpecial && len(line) > 0 {
+		d.awaitingSpecial = false
+		stats.Unexpected += escapeUnexpectedTable[line[0]]
+		line[0] = escapedTable[line[0]]
+		i, j = 1, 1
+	}
+	for i < len(line) {
+		// eight chars at once when none of them is an escape or
+		// unexpected, which is nearly always
+		if len(line)-i >= 8 {
+			w := binary.LittleEndian.Uint64(line[i:])
+			if !hasZero(w) && !hasZero(w^('\r'*lanes1)) && !hasZero(w^('='*lanes1)) {
+				binary.LittleEndian.PutUint64(line[j:], sub42(w))
+				i, j = i+8, j+8
+				continue
+			}
+		}
+		c := line[i]
+		if c != '=' {
+			stats.Unexpected += plainUnexpectedTable[c]
+			line[j] = plainTable[c]
+			i, j = i+1, j+1
 			continue
-			// normal char, yenc42
-		} else {
-			line[j] = (line[i] - 42) & 255
 		}
+		stats.Escaped++
+		if i+1 == len(line) {
+			d.awaitingSpecial = true
+			break
+		}
+		c = line[i+1]
+		stats.Unexpected += escapeUnexpectedTable[c]
+		line[j] = escapedTable[c]
+		i, j = i+2, j+1
 	}
-	// return the new (possibly shorter) slice
-	// shorter because of the escaped chars
-	return line[:len(line)-(i-j)]
+	return line[:j]
 }
 
-func (d *decoder) readBody() error {
-	// ready the part body 
-	d.part.Body = make([]byte, 0)
+// apply the escape policy to an escape left open at the end of a line,
+// b is the line decoded so far and eol its line ending
+func (d *decoder) lineEndEscape(b, eol []byte) ([]byte, error) {
+	stats := &d.part.Stats
+	stats.LineEndEscapes++
+	switch d.escapePolicy {
+	case EscapeLineBreak:
+		d.awaitingSpecial = false
+		if len(eol) > 0 {
+			stats.Unexpected += escapeUnexpectedTable[eol[0]]
+			// b was decoded in place so the = it stops short of is
+			// free to take the escaped char
+			b = append(b, escapedTable[eol[0]])
+		}
+	case EscapeError:
+		return nil, fmt.Errorf("%w on line %d of part %d of %s", ErrEscapeAtLineEnd, stats.Lines, d.part.Number, d.part.Name)
+	}
+	return b, nil
+}
+
+// append encoded input to the part's raw copy when keeping it
+func (d *decoder) keepRaw(b []byte) {
+	if d.withRaw {
+		d.part.Raw = append(d.part.Raw, b...)
+	}
+}
+
+// ready the decoder for the body of d.part
+func (d *decoder) startBody() {
 	// reset special
 	d.awaitingSpecial = false
+	// the whole file crc starts over with each file of a multi-file
+	// stream, only sets read in order are checked against it
+	if !d.multipart || d.part.Number <= 1 {
+		d.crc32 = 0
+		d.crcHash.Reset()
+	}
 	// setup crc hash
-	d.part.crcHash = crc32.NewIEEE()
+	if !d.skipCRC {
+		d.part.crcHash = crc32.NewIEEE()
+	}
+	if d.withCRC64 {
+		d.part.crc64Hash = crc64.New(crc64Table)
+	}
+	if d.fingerprints {
+		d.part.sha256Hash = sha256.New()
+	}
+}
+
+// read a line, which is only valid until the next read
+func (d *decoder) readLine() ([]byte, error) {
+	line, err := d.buf.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, err
+	}
+	d.scratch = d.appendBuf(d.scratch[:0], line)
+	for err == bufio.ErrBufferFull {
+		line, err = d.buf.ReadSlice('\n')
+		d.scratch = d.appendBuf(d.scratch, line)
+		// a long body line is fine, a long =yend line isn't
+		if d.longHeader(len(d.scratch)) && lineVersion(d.scratch, "=yend", d.yenc2) > 0 {
+			return nil, d.checkHeaderLine(len(d.scratch))
+		}
+	}
+	return d.scratch, err
+}
+
+// append b to dst, growing dst through the allocator when there is one
+func (d *decoder) appendBuf(dst, b []byte) []byte {
+	if d.alloc == nil || len(dst)+len(b) <= cap(dst) {
+		return append(dst, b...)
+	}
+	grown := d.alloc.Get(max(2*cap(dst), len(dst)+len(b)))[:len(dst)]
+	copy(grown, dst)
+	if cap(dst) > 0 {
+		d.alloc.Put(dst[:cap(dst)])
+	}
+	return append(grown, b...)
+}
+
+// read and decode the next line of the body, done is set once the
+// trailer has been read
+func (d *decoder) readBodyLine() (b []byte, done bool, err error) {
+	d.startLap()
+	line, err := d.readLine()
+	d.lap(stageRead)
+	// a last line without a newline is still a line
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, false, err
+	}
+	d.keepRaw(line)
+	if d.metrics != nil {
+		d.metrics.EncodedBytes += int64(len(line))
+	}
+	orig := line
+	// strip linefeeds (some use CRLF some LF)
+	line = bytes.TrimRight(line, "\r\n")
+	eol := orig[len(line):]
+	for _, f := range d.lineFuncs {
+		line = f(d.part, line)
+	}
+	// check for =yend
+	if lineVersion(line, "=yend", d.yenc2) > 0 {
+		if err := d.checkHeaderLine(len(orig)); err != nil {
+			return nil, false, err
+		}
+		if d.withLines {
+			d.part.TrailerLine = string(orig)
+		}
+		if d.part.crc64Hash != nil {
+			d.part.CRC64 = d.part.crc64Hash.Sum64()
+		}
+		if d.part.Begin <= 1 {
+			d.part.DetectedType = DetectType(d.part.head)
+		}
+		return nil, true, d.parseTrailer(string(line))
+	}
+	d.part.Stats.Lines++
+	if d.part.Line > 0 && len(line) > d.part.Line+1 {
+		d.part.Stats.LongLines++
+	}
+	if n := d.trailingWhitespace(line); n > 0 {
+		d.part.Stats.TrailingWhitespace++
+		switch d.whitespacePolicy {
+		case WhitespaceStrip:
+			line = line[:len(line)-n]
+		case WhitespaceError:
+			return nil, false, fmt.Errorf("%w on line %d of part %d of %s", ErrTrailingWhitespace, d.part.Stats.Lines, d.part.Number, d.part.Name)
+		}
+	}
+	// decode
+	b = d.decode(line)
+	// an escape carried over an empty line was counted where its = was
+	if d.awaitingSpecial && len(line) > 0 {
+		if b, err = d.lineEndEscape(b, eol); err != nil {
+			return nil, false, err
+		}
+	}
+	d.lap(stageDecode)
+	// update hashs
+	if !d.skipCRC {
+		d.part.crcHash.Write(b)
+		d.crcHash.Write(b)
+	}
+	if d.part.crc64Hash != nil {
+		d.part.crc64Hash.Write(b)
+	}
+	if d.part.sha256Hash != nil {
+		d.part.sha256Hash.Write(b)
+	}
+	d.lap(stageHash)
+	if d.metrics != nil {
+		d.metrics.DecodedBytes += int64(len(b))
+	}
+	for _, f := range d.chunkFuncs {
+		f(d.part, b)
+	}
+	// keep the first few bytes for sniffing
+	if n := len(d.part.head); n < sniffLen {
+		d.part.head = append(d.part.head, b[:min(len(b), sniffLen-n)]...)
+	}
+	d.part.decoded += int64(len(b))
+	if d.maxPartSize > 0 && d.part.decoded > d.maxPartSize {
+		return nil, false, d.tooLarge(d.part.decoded, d.maxPartSize)
+	}
+	return b, false, nil
+}
+
+// decoded size the headers promise, within reason
+func (p *Part) expectedLen() int {
+	return int(min(max(p.declaredLen(), 0), maxExpectedLen))
+}
+
+// decoded size the headers declare, the =ypart range for multipart
+func (p *Part) declaredLen() int64 {
+	if p.End > 0 {
+		return p.End - max(p.Begin, 1) + 1
+	}
+	return p.HeaderSize
+}
+
+// a bogus header shouldn't be able to demand a huge buffer up front
+const maxExpectedLen = 64 << 20
+
+// most allocated for a body before any data is seen, without an
+// Allocator. Larger parts grow from here, which costs little next to
+// their size.
+const maxPreallocLen = 8 << 20
+
+func (d *decoder) readBody() error {
+	if d.discard {
+		d.startBody()
+		for {
+			if _, done, err := d.readBodyLine(); err != nil || done {
+				return err
+			}
+		}
+	}
+	limit := d.bufferLimit()
+	if n := d.part.declaredLen(); limit > 0 && n > limit {
+		return d.tooLarge(n, limit)
+	}
+	// ready the part body, sized from the headers so it needn't grow
+	if d.alloc != nil {
+		d.part.Body = d.alloc.Get(d.part.expectedLen())[:0]
+		d.part.alloc = d.alloc
+	} else {
+		d.part.Body = make([]byte, 0, min(d.part.expectedLen(), maxPreallocLen))
+	}
+	d.startBody()
 	// each line
 	for {
-		line, err := d.buf.ReadBytes('\n')
-		if err != nil {
+		b, done, err := d.readBodyLine()
+		if err != nil || done {
 			return err
 		}
-		// strip linefeeds (some use CRLF some LF)
-		line = bytes.TrimRight(line, "\r\n")
-		// check for =yend
-		if len(line) >= 5 && string(line[:5]) == "=yend" {
-			return d.parseTrailer(string(line))
+		if limit > 0 && d.part.decoded > limit {
+			return d.tooLarge(d.part.decoded, limit)
 		}
-		// decode
-		b := d.decode(line)
-		// update hashs
-		d.part.crcHash.Write(b)
-		d.crcHash.Write(b)
-		// decode
-		d.part.Body = append(d.part.Body, b...)
+		d.part.Body = d.appendBuf(d.part.Body, b)
 	}
-	return nil
 }
 
-func (d *decoder) run() error {
-	// init hash
-	d.crcHash = crc32.NewIEEE()
-	// for each part
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// input is read in blocks this big. Body lines are found in the block
+// with ReadSlice and decoded where they lie, so no line is copied or
+// allocated on its own.
+const readBufferSize = 64 << 10
+
+// read buffers are reused between decodes so a downloader working
+// through millions of articles doesn't allocate one for each
+var readerPool = sync.Pool{New: func() interface{} {
+	return bufio.NewReaderSize(nil, readBufferSize)
+}}
+
+func newDecoder(input io.Reader, opts ...Option) *decoder {
+	d := &decoder{
+		crcHash:       crc32.NewIEEE(),
+		maxHeaderLine: DefaultMaxHeaderLine,
+		maxPartSize:   -1,
+		maxParts:      DefaultMaxParts,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.buf = readerPool.Get().(*bufio.Reader)
+	d.buf.Reset(d.wrap(input))
+	return d
+}
+
+// wrap input in the readers the options ask for
+func (d *decoder) wrap(input io.Reader) io.Reader {
+	if d.timeout > 0 {
+		d.deadline = newDeadlineReader(input, d.timeout)
+		input = d.deadline
+	}
+	if d.rawTee != nil {
+		input = io.TeeReader(input, d.rawTee)
+	}
+	if d.nntp {
+		input = NewArticleReader(input)
+	}
+	return input
+}
+
+// reset readies d to decode input from the start, keeping its options
+// and buffers
+func (d *decoder) reset(input io.Reader) {
+	d.multipart, d.total = false, 0
+	d.parts, d.part = nil, nil
+	d.crc32 = 0
+	d.crcHash.Reset()
+	d.awaitingSpecial = false
+	d.lastNumber, d.lastEnd = 0, 0
+	d.skipped = 0
+	d.clearDeadline()
+	d.buf.Reset(d.wrap(input))
+}
+
+// hand the read buffer back once d is done with. Nothing decoded
+// points into it, bodies and lines kept are copies.
+func (d *decoder) release() {
+	d.clearDeadline()
+	d.buf.Reset(nil)
+	readerPool.Put(d.buf)
+	d.buf = nil
+}
+
+// clear any deadline WithTimeout set on the input
+func (d *decoder) clearDeadline() {
+	if d.deadline != nil {
+		d.deadline.clear()
+		d.deadline = nil
+	}
+}
+
+// decode the next part from the input, a part that fails validation
+// is returned along with the error
+func (d *decoder) next() (*Part, error) {
 	for {
 		// create a part
 		d.part = new(Part)
-		// read the header
-		if err := d.readHeader(); err != nil {
-			return err
-		}
-		// read part header if available
-		if d.multipart {
-			if err := d.readPartHeader(); err != nil {
-				return err
+		if err := d.readHeaders(); err != nil {
+			// input ending after =ybegin has always been taken as the
+			// end of the stream here
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
 			}
+			return nil, err
 		}
-		// decode the part body
-		if err := d.readBody(); err != nil {
-			return err
+		if d.wanted() {
+			break
+		}
+		if err := d.skipBody(); err != nil {
+			return nil, err
 		}
-		// add part to list
+	}
+	// decode the part body
+	span := d.partSpan("yenc.body")
+	err := d.readBody()
+	span.SetAttribute(attrBytes, d.part.decoded)
+	span.End(err)
+	if err == io.EOF {
+		// keep what was recovered of a cut short part
 		d.parts = append(d.parts, d.part)
-		// validate part
-		if err := d.part.validate(); err != nil {
+		return d.part, fmt.Errorf("%w in part %d of %s", ErrTrailerMissing, d.part.Number, d.part.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// add part to list
+	d.parts = append(d.parts, d.part)
+	// validate part
+	span = d.partSpan("yenc.validate")
+	err = d.checkPart()
+	span.End(err)
+	return d.part, err
+}
+
+// read the =ybegin line, and the =ypart line if multipart. io.EOF means
+// the input ended before another part, io.ErrUnexpectedEOF that it
+// ended between the two lines.
+func (d *decoder) readHeaders() error {
+	span := startSpan(d.tracer, "yenc.header")
+	err := d.readHeader()
+	if err == nil && d.multipart {
+		err = unexpected(d.readPartHeader())
+	}
+	if err == nil && d.maxPartSize > 0 {
+		if n := d.part.declaredLen(); n > d.maxPartSize {
+			err = d.tooLarge(n, d.maxPartSize)
+		}
+	}
+	if err == nil && d.maxParts > 0 && len(d.parts) >= d.maxParts {
+		err = d.overLimit("parts", int64(len(d.parts)+1), int64(d.maxParts))
+	}
+	if err == io.EOF {
+		// running out of input between parts isn't a failure
+		span.End(nil)
+	} else {
+		span.End(err)
+	}
+	return err
+}
+
+// start a span about the current part
+func (d *decoder) partSpan(name string) Span {
+	span := startSpan(d.tracer, name)
+	span.SetAttribute(attrName, d.part.Name)
+	span.SetAttribute(attrPart, int64(d.part.Number))
+	return span
+}
+
+// whether the current part passes the filter
+func (d *decoder) wanted() bool {
+	return d.filter == nil || d.filter(d.part)
+}
+
+// read past the body of an unwanted part without decoding or hashing
+// it. Lines are scanned for =yend rather than trusting the declared
+// size, which if too large would take the parts after it along.
+func (d *decoder) skipBody() error {
+	d.skipped++
+	start := true
+	for {
+		line, err := d.buf.ReadSlice('\n')
+		if start && lineVersion(line, "=yend", d.yenc2) > 0 {
+			return nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
 			return err
 		}
+		// a long line comes in pieces, only the first can be =yend
+		start = err == nil
 	}
+}
+
+// validate the part just decoded, crc failures become warnings if
+// the decoder is configured that way
+func (d *decoder) checkPart() error {
+	if err := d.part.validateSize(); err != nil {
+		if err := d.fixSize(err); err != nil {
+			return err
+		}
+	}
+	if d.skipCRC {
+		return nil
+	}
+	return d.warnOr(d.part, d.part.validateCRC())
+}
+
+// apply the size policy to a part whose length is wrong
+func (d *decoder) fixSize(err error) error {
+	p := d.part
+	switch {
+	case d.sizePolicy == SizeAccept,
+		d.sizePolicy != SizeError && p.Body == nil:
+	case d.sizePolicy == SizeTruncate && p.decoded > p.Size && p.Size >= 0:
+		p.Body = p.Body[:p.Size]
+	// never pad beyond the size of the whole file, or by more than a
+	// bogus size could make us allocate
+	case d.sizePolicy == SizePad && p.decoded < p.Size && (p.HeaderSize == 0 || p.Size <= p.HeaderSize) &&
+		p.Size-p.decoded <= maxExpectedLen:
+		p.Body = append(p.Body, make([]byte, p.Size-p.decoded)...)
+	default:
+		return err
+	}
+	p.Warnings = append(p.Warnings, err)
 	return nil
 }
 
-// return a single part from yenc data
-func Decode(input io.Reader) (*Part, error) {
-	d := &decoder{buf: bufio.NewReader(input)}
-	if err := d.run(); err != nil && err != io.EOF {
-		return nil, err
+// record err as a warning on p instead of returning it, when crc
+// mismatches are only warnings
+func (d *decoder) warnOr(p *Part, err error) error {
+	if err != nil && d.crcWarnings {
+		p.Warnings = append(p.Warnings, err)
+		return nil
 	}
+	return err
+}
+
+func (d *decoder) run() error {
+	// for each part
+	for {
+		if _, err := d.next(); err != nil {
+			return err
+		}
+	}
+}
+
+// checks once the input is exhausted
+func (d *decoder) finish() error {
 	if len(d.parts) == 0 {
-		return nil, fmt.Errorf("no yenc parts found")
+		if d.skipped > 0 {
+			return fmt.Errorf("%w matching the filter", ErrNoParts)
+		}
+		return ErrNoParts
+	}
+	// the file as a whole can't be checked when parts were skipped
+	if d.skipped > 0 {
+		return nil
 	}
 	// validate multipart only if all parts are present
-	if !d.multipart || len(d.parts) == d.parts[len(d.parts)-1].Number {
-		if err := d.validate(); err != nil {
-			return nil, err
+	if last := d.parts[len(d.parts)-1]; !d.multipart || len(d.parts) == last.Number {
+		// only a set with a known total can be known to be complete
+		if d.multipart && d.total == len(d.parts) {
+			if err := d.checkSizes(); err != nil {
+				if d.sizePolicy == SizeError {
+					return err
+				}
+				last.Warnings = append(last.Warnings, err)
+			}
+		}
+		if d.skipCRC {
+			return nil
+		}
+		span := startSpan(d.tracer, "yenc.validate")
+		span.SetAttribute(attrName, last.Name)
+		err := d.warnOr(last, d.validate())
+		span.End(err)
+		return err
+	}
+	return nil
+}
+
+// errors for data that can't be decoded or fails its checks, wrapped
+// with the details so they can be told apart with errors.Is
+var (
+	// the input holds no yenc data
+	ErrNoParts = errors.New("yenc: no yenc parts found")
+	// a part's decoded length differs from its trailer
+	ErrSizeMismatch = errors.New("yenc: size check failed")
+	// a part's or the whole file's data doesn't match the crc given
+	ErrCRCMismatch = errors.New("yenc: crc check failed")
+	// the input ended inside a part, before its =yend line
+	ErrTrailerMissing = errors.New("yenc: input ended before =yend")
+	// a body line ends in a space or tab that wasn't escaped, when
+	// decoding WithWhitespacePolicy(WhitespaceError)
+	ErrTrailingWhitespace = errors.New("yenc: unescaped whitespace at end of line")
+	// a body line ends in an escape, when decoding
+	// WithEscapePolicy(EscapeError)
+	ErrEscapeAtLineEnd = errors.New("yenc: escape at end of line")
+)
+
+// ErrInconsistentSize is returned when the parts of a complete multipart
+// set do not add up to the size given in the =ybegin header
+var ErrInconsistentSize = errors.New("yenc: part sizes inconsistent with file size")
+
+// check a complete multipart set adds up to the header size
+func (d *decoder) checkSizes() error {
+	var sum int64
+	for _, p := range d.parts {
+		sum += p.Size
+	}
+	last := d.parts[len(d.parts)-1]
+	if sum != last.HeaderSize {
+		return fmt.Errorf("%w: parts total %d bytes, header says %d", ErrInconsistentSize, sum, last.HeaderSize)
+	}
+	if last.End != last.HeaderSize {
+		return fmt.Errorf("%w: last part ends at %d, header says %d", ErrInconsistentSize, last.End, last.HeaderSize)
+	}
+	return nil
+}
+
+// Decode decodes every part in input and returns the first. Use
+// DecodeAll or DecodeResult to get at all of them.
+func Decode(input io.Reader, opts ...Option) (*Part, error) {
+	d := newDecoder(input, opts...)
+	defer d.release()
+	err := d.run()
+	if err == io.EOF {
+		err = d.finish()
+	}
+	if err != nil {
+		if d.partial && len(d.parts) > 0 {
+			return d.parts[0], err
 		}
+		return nil, err
 	}
 	return d.parts[0], nil
 }
+
+// read the headers of the first part passing the filter, skipping the
+// bodies of those before it
+func (d *decoder) readWanted() error {
+	for {
+		d.part = new(Part)
+		if err := d.readHeaders(); err != nil {
+			if err == io.EOF {
+				err = d.finish()
+			}
+			return err
+		}
+		if d.wanted() {
+			return nil
+		}
+		if err := d.skipBody(); err != nil {
+			return unexpected(err)
+		}
+	}
+}
+
+// DecodeInto decodes the first part in r, or the first passing
+// WithFilter, into dst, returning the number of bytes written and the
+// values from the part's trailer. The data is checked as with Decode.
+// If the part's header or data needs more room than dst has the error
+// wraps io.ErrShortBuffer.
+func DecodeInto(dst []byte, r io.Reader, opts ...Option) (n int, trailer Trailer, err error) {
+	d := newDecoder(r, opts...)
+	defer d.release()
+	if err := d.readWanted(); err != nil {
+		return 0, trailer, err
+	}
+	if need := d.part.expectedLen(); need > len(dst) {
+		return 0, trailer, fmt.Errorf("yenc: part of %d bytes does not fit in %d: %w", need, len(dst), io.ErrShortBuffer)
+	}
+	d.startBody()
+	for {
+		b, done, err := d.readBodyLine()
+		if err != nil {
+			return n, trailer, unexpected(err)
+		}
+		if done {
+			break
+		}
+		if n+len(b) > len(dst) {
+			return n, trailer, fmt.Errorf("yenc: data does not fit in %d bytes: %w", len(dst), io.ErrShortBuffer)
+		}
+		n += copy(dst[n:], b)
+	}
+	trailer = Trailer{Part: d.part.Number, Size: d.part.Size, PartCRC32: d.part.CRC32, CRC32: d.crc32, Headers: d.part.TrailerHeaders}
+	return n, trailer, d.checkPart()
+}
+
+// DecodeTo decodes the first part in src, or the first passing
+// WithFilter, writing its data to dst a line at a time instead of
+// holding it in memory, and returns what its headers said. The data is
+// checked as with Decode; if it fails the headers are returned along
+// with the error.
+func DecodeTo(dst io.Writer, src io.Reader, opts ...Option) (*PartInfo, error) {
+	d := newDecoder(src, opts...)
+	defer d.release()
+	if err := d.readWanted(); err != nil {
+		return nil, err
+	}
+	info := d.partInfo(d.part)
+	d.startBody()
+	for {
+		b, done, err := d.readBodyLine()
+		if err != nil {
+			return &info, unexpected(err)
+		}
+		if done {
+			break
+		}
+		if _, err := dst.Write(b); err != nil {
+			return &info, err
+		}
+	}
+	return &info, d.checkPart()
+}
+
+// Verify decodes every part in input and checks their sizes and crcs as
+// Decode does, without keeping the decoded data, and returns what the
+// first part's headers said. It is for indexers and health checks that
+// only need to know an article is intact. If a check fails the headers
+// are returned along with the error.
+func Verify(input io.Reader, opts ...Option) (*PartInfo, error) {
+	d := newDecoder(input, opts...)
+	defer d.release()
+	d.discard = true
+	err := d.run()
+	if err == io.EOF {
+		err = d.finish()
+	}
+	if len(d.parts) == 0 {
+		return nil, err
+	}
+	info := d.partInfo(d.parts[0])
+	return &info, err
+}
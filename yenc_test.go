@@ -1,7 +1,16 @@
 package yenc
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+	"maps"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -23,7 +32,7 @@ func TestMultipartDecode(t *testing.T) {
 	}
 	part, err := Decode(f)
 	if err != nil {
-		t.Errorf("expected to decode: " + err.Error())
+		t.Errorf("expected to decode: %v", err)
 	}
 	if part.Name != "joystick.jpg" {
 		t.Errorf("expected part name %s got %s", "joystick.jpg", part.Name)
@@ -31,3 +40,879 @@ func TestMultipartDecode(t *testing.T) {
 	// out,_ := os.Create("joystick.jpg")
 	// out.Write(part.Body)
 }
+
+func TestDecodeWithCRC64(t *testing.T) {
+	f, err := os.Open("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	part, err := Decode(f, WithCRC64())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if sum := crc64.Checksum(part.Body, crc64.MakeTable(crc64.ECMA)); part.CRC64 != sum {
+		t.Errorf("expected crc64 %x got %x", sum, part.CRC64)
+	}
+}
+
+func TestDecodeWithCRCWarnings(t *testing.T) {
+	good, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	bad := bytes.Replace(good, []byte("crc32=ded29f4f"), []byte("crc32=00000001"), 1)
+	if _, err := Decode(bytes.NewReader(bad)); err == nil {
+		t.Fatal("expected crc mismatch to fail decode")
+	}
+	part, err := Decode(bytes.NewReader(bad), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode with crc warnings: " + err.Error())
+	}
+	if len(part.Warnings) == 0 || len(part.Body) != 584 {
+		t.Errorf("expected part with warnings got %d warnings and %d bytes", len(part.Warnings), len(part.Body))
+	}
+}
+
+func TestDecodeWithSizePolicy(t *testing.T) {
+	good, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	tests := []struct {
+		size     string
+		policy   SizePolicy
+		expected int
+	}{
+		{"size=600 ", SizePad, 600},
+		{"size=500 ", SizeTruncate, 500},
+		{"size=500 ", SizeAccept, 584},
+		{"size=600 ", SizeTruncate, -1},
+		{"size=600 ", SizeAccept, 584},
+		{"size=500 ", SizeError, -1},
+	}
+	for _, test := range tests {
+		input := bytes.Replace(good, []byte("size=584 "), []byte(test.size), -1)
+		part, err := Decode(bytes.NewReader(input), WithSizePolicy(test.policy), WithCRCWarnings())
+		if test.expected < 0 {
+			if err == nil {
+				t.Errorf("%s policy %d: expected error", test.size, test.policy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s policy %d: expected to decode: %v", test.size, test.policy, err)
+			continue
+		}
+		if len(part.Body) != test.expected || len(part.Warnings) == 0 {
+			t.Errorf("%s policy %d: expected %d bytes with warnings got %d bytes %v",
+				test.size, test.policy, test.expected, len(part.Body), part.Warnings)
+		}
+	}
+}
+
+func TestDecodeWithRaw(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	part, err := Decode(bytes.NewReader(raw), WithRaw())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if !bytes.Equal(part.Raw, raw) {
+		t.Errorf("expected raw copy of %d bytes got %d", len(raw), len(part.Raw))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	tests := []struct {
+		input    []byte
+		expected error
+	}{
+		{bytes.Replace(raw, []byte("crc32=ded29f4f"), []byte("crc32=eed29f4f"), 1), ErrCRCMismatch},
+		{bytes.Replace(raw, []byte("=yend size=584"), []byte("=yend size=583"), 1), ErrSizeMismatch},
+		{raw[:len(raw)/2], ErrTrailerMissing},
+		{[]byte("no yenc here\r\n"), ErrNoParts},
+	}
+	for i, test := range tests {
+		if _, err := Decode(bytes.NewReader(test.input)); !errors.Is(err, test.expected) {
+			t.Errorf("%d: expected %v got %v", i, test.expected, err)
+		}
+	}
+}
+
+func TestDecodeWithSkipCRC(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	bad := bytes.Replace(raw, []byte("crc32=ded29f4f"), []byte("crc32=eed29f4f"), 1)
+	if _, err := Decode(bytes.NewReader(bad)); err == nil {
+		t.Fatal("expected crc error without WithSkipCRC")
+	}
+	if _, err := Decode(bytes.NewReader(bad), WithSkipCRC()); err != nil {
+		t.Errorf("expected crc to be skipped got %v", err)
+	}
+	short := bytes.Replace(raw, []byte("=yend size=584"), []byte("=yend size=583"), 1)
+	if _, err := Decode(bytes.NewReader(short), WithSkipCRC()); err == nil {
+		t.Error("expected size to still be checked")
+	}
+}
+
+func TestDecodeWithMaxPartSize(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	if _, err := Decode(bytes.NewReader(raw), WithMaxPartSize(584)); err != nil {
+		t.Errorf("expected part within the limit to decode got %v", err)
+	}
+	if _, err := Decode(bytes.NewReader(raw), WithMaxPartSize(500)); err == nil {
+		t.Error("expected declared size over the limit to fail")
+	}
+	// the header understates the size
+	lying := bytes.Replace(raw, []byte("size=584 name"), []byte("size=10 name"), 1)
+	if _, err := io.ReadAll(NewDecoder(bytes.NewReader(lying), WithMaxPartSize(500))); err == nil {
+		t.Error("expected data over the limit to fail")
+	}
+}
+
+func TestDecodeWithLenient(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	bad := bytes.Replace(raw, []byte("=yend size=584 crc32=ded29f4f"), []byte("=yend size=600 crc32=eed29f4f"), 1)
+	part, err := Decode(bytes.NewReader(bad), WithLenient())
+	if err != nil {
+		t.Fatal("expected lenient decode: " + err.Error())
+	}
+	if len(part.Body) != 584 || len(part.Warnings) < 2 {
+		t.Errorf("expected 584 bytes with size and crc warnings got %d %v", len(part.Body), part.Warnings)
+	}
+}
+
+func TestDecodeWithPartial(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	full, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	bad := bytes.Replace(raw, []byte("crc32=ded29f4f"), []byte("crc32=eed29f4f"), 1)
+	part, err := Decode(bytes.NewReader(bad), WithPartial())
+	if !errors.Is(err, ErrCRCMismatch) || part == nil || !bytes.Equal(part.Body, full.Body) {
+		t.Errorf("expected damaged part with crc error got %v", err)
+	}
+	// cut short inside the second line of data
+	cut := bytes.Index(raw, []byte("\n")) + 200
+	res, err := DecodeResult(bytes.NewReader(raw[:cut]), WithPartial())
+	if !errors.Is(err, ErrTrailerMissing) || res == nil || len(res.Parts) != 1 {
+		t.Fatalf("expected truncated part with error got %v", err)
+	}
+	if body := res.Parts[0].Body; len(body) == 0 || !bytes.HasPrefix(full.Body, body) {
+		t.Errorf("expected the start of the data got %d bytes", len(body))
+	}
+	if part, err := Decode(bytes.NewReader(raw[:cut])); part != nil || err == nil {
+		t.Error("expected nothing back without WithPartial")
+	}
+}
+
+func TestDecodeWithRawTee(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	input := append([]byte("Subject: test\r\n\r\n"), raw...)
+	var tee bytes.Buffer
+	if _, err := io.Copy(io.Discard, NewDecoder(bytes.NewReader(input), WithRawTee(&tee))); err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if !bytes.Equal(tee.Bytes(), input) {
+		t.Errorf("expected a copy of all %d bytes read got %d", len(input), tee.Len())
+	}
+}
+
+func TestDecodeWithHeaderLines(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	part, err := Decode(bytes.NewReader(raw), WithHeaderLines())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	lines := strings.SplitAfter(string(raw), "\n")
+	if part.HeaderLine != lines[0] || part.PartLine != lines[1] {
+		t.Errorf("expected header lines as read got %q %q", part.HeaderLine, part.PartLine)
+	}
+	if !strings.HasPrefix(part.TrailerLine, "=yend") || !strings.HasSuffix(string(raw), part.TrailerLine) {
+		t.Errorf("expected trailer line as read got %q", part.TrailerLine)
+	}
+	if part, _ := Decode(bytes.NewReader(raw)); part.HeaderLine != "" {
+		t.Errorf("expected header lines only to be kept when asked")
+	}
+}
+
+func TestDecodeStats(t *testing.T) {
+	input := "=ybegin line=6 size=12 name=x\r\n" +
+		// escaped =, an escape that was not needed and a raw NUL
+		"=}=qa\x00\r\n" +
+		// too long
+		"bbbbbbbb\r\n" +
+		"=yend size=12\r\n"
+	part, err := Decode(strings.NewReader(input), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	expected := Stats{Lines: 2, Escaped: 2, LongLines: 1, Unexpected: 2}
+	if part.Stats != expected {
+		t.Errorf("expected stats %+v got %+v", expected, part.Stats)
+	}
+}
+
+func TestDecodeWithLineAndChunkFuncs(t *testing.T) {
+	// a gateway that appended junk to every line
+	input := "=ybegin line=128 size=3 name=x\r\n" + "\x8b\x8c\x8d#JUNK\r\n" + "=yend size=3 crc32=352441c2\r\n"
+	var lines, chunks int
+	var decoded []byte
+	part, err := Decode(strings.NewReader(input),
+		WithLineFunc(func(p *Part, line []byte) []byte {
+			lines++
+			if i := bytes.Index(line, []byte("#JUNK")); i > -1 {
+				return line[:i]
+			}
+			return line
+		}),
+		WithChunkFunc(func(p *Part, b []byte) {
+			chunks++
+			decoded = append(decoded, b...)
+		}),
+	)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if string(part.Body) != "abc" || string(decoded) != "abc" {
+		t.Errorf("expected abc got %q / %q", part.Body, decoded)
+	}
+	if lines != 2 || chunks != 1 {
+		t.Errorf("expected 2 lines and 1 chunk got %d and %d", lines, chunks)
+	}
+}
+
+// an Allocator that counts what it hands out
+type countingAllocator struct {
+	gets, puts int
+}
+
+func (a *countingAllocator) Get(n int) []byte {
+	a.gets++
+	return make([]byte, n)
+}
+
+func (a *countingAllocator) Put(b []byte) {
+	a.puts++
+}
+
+func TestDecodeWithAllocator(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	a := new(countingAllocator)
+	part, err := Decode(bytes.NewReader(raw), WithAllocator(a))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	// the body is sized from the =ypart range so needs no growing
+	if a.gets != 1 || a.puts != 0 || cap(part.Body) != 11250 {
+		t.Errorf("expected one body sized allocation got %d gets %d puts cap %d", a.gets, a.puts, cap(part.Body))
+	}
+	// lines longer than the read buffer go through scratch space
+	size := readBufferSize + 8000
+	long := fmt.Sprintf("=ybegin line=%d size=%d name=x\r\n%s\r\n=yend size=%d\r\n", size, size, strings.Repeat("a", size), size)
+	part, err = Decode(strings.NewReader(long), WithAllocator(a), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if len(part.Body) != size || a.gets < 3 {
+		t.Errorf("expected %d bytes via the allocator got %d after %d gets", size, len(part.Body), a.gets)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	a := new(countingAllocator)
+	res, err := DecodeResult(bytes.NewReader(raw), WithAllocator(a))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	res.Release()
+	res.Release()
+	if a.puts != 1 || res.Parts[0].Body != nil {
+		t.Errorf("expected body to be put back once got %d puts", a.puts)
+	}
+}
+
+func TestDecodeAfterBOM(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	for _, prefix := range []string{"\xef\xbb\xbf", "\r\n\n  ", "\xef\xbb\xbf\r\n\t"} {
+		part, err := Decode(strings.NewReader(prefix+string(raw)), WithHeaderLines())
+		if err != nil {
+			t.Fatalf("%q: expected to decode: %v", prefix, err)
+		}
+		if part.Name != "testfile.txt" || len(part.Body) != 584 {
+			t.Errorf("%q: unexpected part %q of %d bytes", prefix, part.Name, len(part.Body))
+		}
+		if part.Stats.Preamble != 1 || !strings.HasPrefix(part.HeaderLine, prefix[strings.LastIndex(prefix, "\n")+1:]) {
+			t.Errorf("%q: expected the preamble to be counted and kept got %d %q", prefix, part.Stats.Preamble, part.HeaderLine)
+		}
+	}
+}
+
+func TestDecodeWithResync(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	joined := "Path: news.example.com!not-for-mail" + string(raw)
+	if _, err := Decode(strings.NewReader(joined)); err == nil {
+		t.Errorf("expected the header to be missed without WithResync")
+	}
+	part, err := Decode(strings.NewReader(joined), WithResync())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if part.Name != "testfile.txt" || len(part.Body) != 584 || part.Stats.Resyncs != 1 {
+		t.Errorf("unexpected part %q of %d bytes with %d resyncs", part.Name, len(part.Body), part.Stats.Resyncs)
+	}
+}
+
+func TestDecodeTrailerWithoutSize(t *testing.T) {
+	var buf bytes.Buffer
+	m := &MultipartEncoder{PartSize: 100}
+	data := encodeTestData(250)
+	m.Encode(bytes.NewReader(data), "x.bin", 250, func(part, total int) (io.Writer, error) {
+		return &buf, nil
+	})
+	input := bytes.ReplaceAll(buf.Bytes(), []byte("=yend size=100 "), []byte("=yend "))
+	res, err := DecodeResult(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if p := res.Parts[0]; p.Size != 100 || len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0].Error(), "=ypart range") {
+		t.Errorf("expected size from the =ypart range got %d %v", p.Size, p.Warnings)
+	}
+
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	input = bytes.Replace(raw, []byte("=yend size=584 "), []byte("=yend "), 1)
+	part, err := Decode(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if part.Size != 584 || len(part.Warnings) != 1 {
+		t.Errorf("expected size from the =ybegin header got %d %v", part.Size, part.Warnings)
+	}
+	// the header size still catches a short body
+	short := bytes.Replace(input, []byte("=ybegin line=128 size=584"), []byte("=ybegin line=128 size=600"), 1)
+	if _, err := Decode(bytes.NewReader(short)); err == nil {
+		t.Errorf("expected a size mismatch against the header")
+	}
+}
+
+func TestDecodeWithInferOffsets(t *testing.T) {
+	var buf bytes.Buffer
+	m := &MultipartEncoder{PartSize: 100}
+	data := encodeTestData(250)
+	m.Encode(bytes.NewReader(data), "x.bin", 250, func(part, total int) (io.Writer, error) {
+		return &buf, nil
+	})
+	// the first =ypart line is lost and the second is garbled
+	input := bytes.Replace(buf.Bytes(), []byte("=ypart begin=1 end=100\r\n"), nil, 1)
+	input = bytes.Replace(input, []byte("begin=101 end=200"), []byte("begin=1O1 end=2OO"), 1)
+	if _, err := DecodeResult(bytes.NewReader(input)); err == nil {
+		t.Errorf("expected to fail without WithInferOffsets")
+	}
+	res, err := DecodeResult(bytes.NewReader(input), WithInferOffsets())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	expected := [][2]int64{{1, 100}, {101, 200}, {201, 250}}
+	for i, p := range res.Parts {
+		if p.Begin != expected[i][0] || p.End != expected[i][1] {
+			t.Errorf("part %d: expected range %v got %d-%d", p.Number, expected[i], p.Begin, p.End)
+		}
+		if inferred := len(p.Warnings) > 0; inferred != (i < 2) {
+			t.Errorf("part %d: unexpected warnings %v", p.Number, p.Warnings)
+		}
+	}
+	var body []byte
+	for _, p := range res.Parts {
+		body = append(body, p.Body...)
+	}
+	if !bytes.Equal(body, data) {
+		t.Errorf("expected the parts to join up to the data")
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	part, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	dst := make([]byte, 1000)
+	n, trailer, err := DecodeInto(dst, bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode into buffer: " + err.Error())
+	}
+	if !bytes.Equal(dst[:n], part.Body) || trailer.Size != 584 || trailer.CRC32 == 0 {
+		t.Errorf("unexpected result %d bytes %+v", n, trailer)
+	}
+	if _, _, err := DecodeInto(make([]byte, 500), bytes.NewReader(raw)); !errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("expected short buffer error got %v", err)
+	}
+	// a header that understates the size is caught while decoding
+	small := bytes.Replace(raw, []byte("size=584"), []byte("size=500"), 1)
+	if _, _, err := DecodeInto(make([]byte, 500), bytes.NewReader(small)); !errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("expected short buffer error got %v", err)
+	}
+	// parts are picked as Decode picks them
+	data := encodeTestData(6000)
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 2000}, data)
+	dst = make([]byte, 2000)
+	n, trailer, err = DecodeInto(dst, io.MultiReader(articles[0], articles[1]), WithParts(2))
+	if err != nil || trailer.Part != 2 || !bytes.Equal(dst[:n], data[2000:4000]) {
+		t.Errorf("expected only part 2 got part %d (%v)", trailer.Part, err)
+	}
+}
+
+// decode a byte at a time as the spec describes, counting unexpected chars
+func referenceDecode(lines [][]byte) ([]byte, int) {
+	var out []byte
+	unexpected, escape := 0, false
+	for _, line := range lines {
+		for _, c := range line {
+			switch {
+			case escape:
+				if !escapable(c - 64) {
+					unexpected++
+				}
+				out = append(out, c-106)
+				escape = false
+			case c == '=':
+				escape = true
+			default:
+				if c == 0 || c == '\r' {
+					unexpected++
+				}
+				out = append(out, c-42)
+			}
+		}
+	}
+	return out, unexpected
+}
+
+func TestDecodeKernelMatchesReference(t *testing.T) {
+	enc := encodeTestData(20000)
+	// escapes, unexpected chars and an escape split across lines
+	for i := 0; i < len(enc); i += 29 {
+		enc[i] = []byte{'=', 0, '\r', '=', 'A'}[i%5]
+	}
+	var lines [][]byte
+	for i := 0; i < len(enc); i += 61 {
+		lines = append(lines, enc[i:min(i+61, len(enc))])
+	}
+	expected, unexpected := referenceDecode(lines)
+	d := &decoder{part: new(Part)}
+	var out []byte
+	for _, line := range lines {
+		out = append(out, d.decode(append([]byte(nil), line...))...)
+	}
+	if !bytes.Equal(out, expected) || d.part.Stats.Unexpected != unexpected {
+		t.Errorf("kernel differs from reference: %d bytes %d unexpected, expected %d and %d",
+			len(out), d.part.Stats.Unexpected, len(expected), unexpected)
+	}
+	for _, w := range []uint64{0, 42 * lanes1, lanesHigh, ^uint64(0), 0x0123456789abcdef} {
+		for k := 0; k < 8; k++ {
+			b := byte(w >> (8 * k))
+			if got := byte(sub42(w) >> (8 * k)); got != b-42 {
+				t.Errorf("sub42(%x) byte %d: expected %x got %x", w, k, b-42, got)
+			}
+		}
+	}
+}
+
+func TestDecodeAllocsPerLine(t *testing.T) {
+	encoded := func(n int) []byte {
+		var buf bytes.Buffer
+		Encode(&buf, "data.bin", encodeTestData(n))
+		return buf.Bytes()
+	}
+	small, large := encoded(1000), encoded(1<<20)
+	allocs := func(raw []byte) float64 {
+		return testing.AllocsPerRun(10, func() {
+			io.Copy(io.Discard, NewDecoder(bytes.NewReader(raw)))
+		})
+	}
+	// thousands more lines shouldn't mean more allocations
+	if a, b := allocs(small), allocs(large); b > a+2 {
+		t.Errorf("expected allocations not to grow with lines got %v then %v", a, b)
+	}
+}
+
+func TestDecodePreallocates(t *testing.T) {
+	data := encodeTestData(100000)
+	var buf bytes.Buffer
+	if err := Encode(&buf, "data.bin", data); err != nil {
+		t.Fatal(err)
+	}
+	part, err := Decode(&buf)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if cap(part.Body) != len(data) {
+		t.Errorf("expected a body of exactly %d bytes got capacity %d", len(data), cap(part.Body))
+	}
+}
+
+func TestDecodeTo(t *testing.T) {
+	raw, err := os.ReadFile("singlepart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open singlepart_test.yenc for testing")
+	}
+	part, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	var out bytes.Buffer
+	info, err := DecodeTo(&out, bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to decode to writer: " + err.Error())
+	}
+	if !bytes.Equal(out.Bytes(), part.Body) || info.Name != "testfile.txt" || info.Size != 584 {
+		t.Errorf("unexpected result %d bytes %+v", out.Len(), info)
+	}
+	bad := bytes.Replace(raw, []byte("crc32=ded29f4f"), []byte("crc32=eed29f4f"), 1)
+	if info, err := DecodeTo(io.Discard, bytes.NewReader(bad)); err == nil || info == nil {
+		t.Errorf("expected crc error with headers got %v %v", info, err)
+	}
+	if _, err := DecodeTo(io.Discard, strings.NewReader("no yenc here\n")); err == nil {
+		t.Error("expected error for input without yenc")
+	}
+	// parts are picked as Decode picks them
+	data := encodeTestData(6000)
+	out.Reset()
+	articles := encodeParts(t, &MultipartEncoder{PartSize: 2000}, data)
+	all := io.MultiReader(articles[0], articles[1], articles[2])
+	if info, err := DecodeTo(&out, all, WithParts(2)); err != nil || info.Number != 2 || !bytes.Equal(out.Bytes(), data[2000:4000]) {
+		t.Errorf("expected only part 2 got %+v (%v)", info, err)
+	}
+	if _, err := DecodeTo(io.Discard, bytes.NewReader(raw), WithParts(2)); !errors.Is(err, ErrNoParts) {
+		t.Errorf("expected ErrNoParts when no part matches got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	raw, err := os.ReadFile("multipart_test.yenc")
+	if err != nil {
+		t.Fatal("could not open multipart_test.yenc for testing")
+	}
+	info, err := Verify(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("expected to verify: " + err.Error())
+	}
+	if info.Name != "joystick.jpg" || info.Number != 1 || info.Begin != 1 || info.End != 11250 {
+		t.Errorf("unexpected headers %+v", info)
+	}
+	bad := bytes.Replace(raw, []byte("pcrc32=bfae5c0b"), []byte("pcrc32=ffffffff"), 1)
+	if info, err := Verify(bytes.NewReader(bad)); !errors.Is(err, ErrCRCMismatch) || info == nil {
+		t.Errorf("expected crc error with headers got %v %v", info, err)
+	}
+	if _, err := Verify(bytes.NewReader(raw[:len(raw)/2])); !errors.Is(err, ErrTrailerMissing) {
+		t.Errorf("expected a cut short part to fail got %v", err)
+	}
+	// a verified body is never held in memory
+	data := encodeTestData(1 << 20)
+	var buf bytes.Buffer
+	Encode(&buf, "big.bin", data)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := Verify(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > uint64(len(data)/4) {
+		t.Errorf("expected verifying not to buffer the body got %d bytes allocated", n)
+	}
+}
+
+func TestPartHeaderFields(t *testing.T) {
+	data := encodeTestData(5000)
+	var buf bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000, LineLength: 64}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &buf, nil
+	})
+	parts, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	for i, p := range parts {
+		if p.Total != 3 || p.HeaderSize != 5000 || p.Line != 64 || p.CRC32 != crc32.ChecksumIEEE(p.Body) {
+			t.Errorf("part %d: unexpected header fields %+v", i+1, p)
+		}
+	}
+	if parts[0].FileCRC32 != 0 || parts[2].FileCRC32 != crc32.ChecksumIEEE(data) {
+		t.Errorf("expected only the last part to carry the file crc got %x and %x", parts[0].FileCRC32, parts[2].FileCRC32)
+	}
+}
+
+func TestPartHeaders(t *testing.T) {
+	input := "=ybegin part=1 total=1 line=128 size=3 x-poster=bob name=a b.bin\r\n" +
+		"=ypart begin=1 end=3 x-hash=sha1=abc\r\n" +
+		"KLM\r\n" +
+		"=yend size=3 part=1 pcrc32=c31bc297 x-extra=1\r\n"
+	p, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	headers := map[string]string{"part": "1", "total": "1", "line": "128", "size": "3", "x-poster": "bob",
+		"name": "a b.bin", "begin": "1", "end": "3", "x-hash": "sha1=abc"}
+	if !maps.Equal(p.Headers, headers) {
+		t.Errorf("unexpected headers %v", p.Headers)
+	}
+	trailer := map[string]string{"size": "3", "part": "1", "pcrc32": "c31bc297", "x-extra": "1"}
+	if !maps.Equal(p.TrailerHeaders, trailer) {
+		t.Errorf("unexpected trailer headers %v", p.TrailerHeaders)
+	}
+}
+
+func TestParseKeywords(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]string
+	}{
+		{"=ybegin line=128 size=3 name=a b  c.bin \r\n", map[string]string{"line": "128", "size": "3", "name": "a b  c.bin"}},
+		{"=ybegin  part=2\ttotal=3 \t line=128 size=9 name=x=y.bin", map[string]string{"part": "2", "total": "3", "line": "128", "size": "9", "name": "x=y.bin"}},
+		{"=ybegin x-filename=f line=128 size=3 name=name=.bin", map[string]string{"x-filename": "f", "line": "128", "size": "3", "name": "name=.bin"}},
+		{"=ypart begin=1\t\tend=3\n", map[string]string{"begin": "1", "end": "3"}},
+		{"=yend size=3 junk crc32=abc", map[string]string{"size": "3", "crc32": "abc"}},
+	}
+	for _, test := range tests {
+		if got := parseKeywords(test.line); !maps.Equal(got, test.want) {
+			t.Errorf("parseKeywords(%q) = %v expected %v", test.line, got, test.want)
+		}
+	}
+	input := "=ybegin part=1\ttotal=1  line=128 size=3 name=my file=1.bin\r\n=ypart  begin=1\tend=3\r\nKLM\r\n=yend\tsize=3  part=1\r\n"
+	p, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if p.Name != "my file=1.bin" || p.Number != 1 || p.Total != 1 || p.End != 3 || p.Size != 3 {
+		t.Errorf("unexpected part %+v", p)
+	}
+}
+
+func TestWhitespacePolicy(t *testing.T) {
+	// "KLM" with a space and tab a broken encoder left unescaped, then
+	// an escaped space
+	input := "=ybegin line=128 size=6 name=x\r\nKLM \t\r\n=`\r\n=yend size=6\r\n"
+	p, err := Decode(strings.NewReader(input), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if string(p.Body) != "!\"#\xf6\xdf\xf6" {
+		t.Errorf("expected trailing whitespace decoded as data got %q", p.Body)
+	}
+	if p.Stats.TrailingWhitespace != 1 {
+		t.Errorf("expected 1 line with trailing whitespace got %d", p.Stats.TrailingWhitespace)
+	}
+	stripped := strings.Replace(input, "size=6", "size=4", 2)
+	p, err = Decode(strings.NewReader(stripped), WithWhitespacePolicy(WhitespaceStrip))
+	if err != nil {
+		t.Fatal("expected to decode stripped: " + err.Error())
+	}
+	if string(p.Body) != "!\"#\xf6" {
+		t.Errorf("expected trailing whitespace dropped got %q", p.Body)
+	}
+	if _, err := Decode(strings.NewReader(input), WithWhitespacePolicy(WhitespaceError)); !errors.Is(err, ErrTrailingWhitespace) {
+		t.Errorf("expected ErrTrailingWhitespace got %v", err)
+	}
+	// an escaped space ending a line is fine
+	if _, err := Decode(strings.NewReader("=ybegin line=128 size=2 name=x\r\nK=`\r\n=yend size=2\r\n"), WithWhitespacePolicy(WhitespaceError)); err != nil {
+		t.Errorf("expected an escaped space to pass got %v", err)
+	}
+	// as is one escaped by an = ending the line before, even when the
+	// line holds nothing else, but not one after an = that escape took
+	for line, seen := range map[string]int{" ": 0, "= \t": 1} {
+		split := "=ybegin line=128 size=2 name=x\r\nK=\r\n" + line + "\r\n=yend size=2\r\n"
+		p, err := Decode(strings.NewReader(split), WithWhitespacePolicy(WhitespaceStrip))
+		if err != nil {
+			t.Errorf("%q: expected to strip only unescaped whitespace got %v", line, err)
+		} else if p.Stats.TrailingWhitespace != seen {
+			t.Errorf("%q: expected %d lines with trailing whitespace got %d", line, seen, p.Stats.TrailingWhitespace)
+		}
+	}
+}
+
+func TestEscapePolicy(t *testing.T) {
+	// an escape split over the line break, "=}" escaping an =
+	input := "=ybegin line=128 size=3 name=x\r\nK=\r\n}L\r\n=yend size=3\r\n"
+	p, err := Decode(strings.NewReader(input), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if string(p.Body) != "!\x13\"" || p.Stats.LineEndEscapes != 1 {
+		t.Errorf("expected the escape carried to the next line got %q and %d", p.Body, p.Stats.LineEndEscapes)
+	}
+	// counted once, however many empty lines it is carried over
+	p, err = Decode(strings.NewReader(strings.Replace(input, "K=\r\n", "K=\r\n\r\n", 1)), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if p.Stats.LineEndEscapes != 1 {
+		t.Errorf("expected 1 escape ending a line got %d", p.Stats.LineEndEscapes)
+	}
+	// or taken to escape the CR, leaving the } as a plain char
+	p, err = Decode(strings.NewReader(strings.Replace(input, "size=3", "size=4", 2)), WithEscapePolicy(EscapeLineBreak), WithCRCWarnings())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if string(p.Body) != "!\xa3S\"" {
+		t.Errorf("expected the line break escaped got %q", p.Body)
+	}
+	if _, err := Decode(strings.NewReader(input), WithEscapePolicy(EscapeError)); !errors.Is(err, ErrEscapeAtLineEnd) {
+		t.Errorf("expected ErrEscapeAtLineEnd got %v", err)
+	}
+}
+
+func TestDecodeYEnc2(t *testing.T) {
+	input := "=ybegin2 part=1 total=1 line=128 size=3 name=x.bin\r\n" +
+		"=ypart2 begin=1 end=3\r\n" +
+		"KLM\r\n" +
+		"=yend2 size=3 part=1 pcrc32=c31bc297\r\n"
+	if _, err := Decode(strings.NewReader(input)); !errors.Is(err, ErrNoParts) {
+		t.Errorf("expected draft headers to be passed over by default got %v", err)
+	}
+	res, err := DecodeResult(strings.NewReader(input), WithYEnc2())
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	p := res.Parts[0]
+	if res.Version != 2 || p.Version != 2 || p.Name != "x.bin" || p.End != 3 || string(p.Body) != "!\"#" {
+		t.Errorf("unexpected result %+v part %+v", res, p)
+	}
+	// version 1 input is still version 1
+	res, err = DecodeResult(strings.NewReader(strings.ReplaceAll(input, "2 ", " ")), WithYEnc2())
+	if err != nil || res.Version != 1 {
+		t.Errorf("expected version 1 got %v", err)
+	}
+}
+
+func TestDecodeWithParts(t *testing.T) {
+	data := encodeTestData(6000)
+	var buf bytes.Buffer
+	m := &MultipartEncoder{PartSize: 2000}
+	m.Encode(bytes.NewReader(data), "data.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &buf, nil
+	})
+	part, err := Decode(bytes.NewReader(buf.Bytes()), WithParts(2))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if part.Number != 2 || !bytes.Equal(part.Body, data[2000:4000]) {
+		t.Errorf("expected part 2 got part %d", part.Number)
+	}
+	// skipped parts are not decoded so their damage goes unnoticed
+	damaged := bytes.Replace(buf.Bytes(), []byte("=yend size=2000 part=1"), []byte("=yend size=1999 part=1"), 1)
+	out, err := io.ReadAll(NewDecoder(bytes.NewReader(damaged), WithParts(2, 3)))
+	if err != nil || !bytes.Equal(out, data[2000:]) {
+		t.Errorf("expected to stream parts 2 and 3 (%v)", err)
+	}
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), WithParts(4)); err == nil {
+		t.Errorf("expected error when no parts match")
+	}
+}
+
+func TestDecodeWithFilter(t *testing.T) {
+	// two files in one dump
+	var buf bytes.Buffer
+	for _, name := range []string{"a.nfo", "b.rar"} {
+		e := NewEncoder(&buf, name, 3)
+		e.Write([]byte(name[:3]))
+		e.Close()
+	}
+	var seen []string
+	res, err := DecodeResult(bytes.NewReader(buf.Bytes()), WithFilter(func(p PartInfo) bool {
+		seen = append(seen, p.Name)
+		return strings.HasSuffix(p.Name, ".rar") && p.Size == 3
+	}))
+	if err != nil {
+		t.Fatal("expected to decode: " + err.Error())
+	}
+	if len(res.Parts) != 1 || string(res.Parts[0].Body) != "b.r" {
+		t.Errorf("expected only b.rar to be decoded got %d parts", len(res.Parts))
+	}
+	if strings.Join(seen, ",") != "a.nfo,b.rar" {
+		t.Errorf("expected filter to see every part got %v", seen)
+	}
+	// parts are skipped by scanning for their trailer, whatever size
+	// they declare
+	wanted := buf.String()[strings.LastIndex(buf.String(), "=ybegin"):]
+	for _, size := range []string{"", " size=99999999"} {
+		input := "=ybegin line=128" + size + " name=a.nfo\r\n\x8b\x8c\x8d\r\n=yend\r\n" + wanted
+		res, err = DecodeResult(strings.NewReader(input), WithFilter(func(p PartInfo) bool {
+			return p.Name == "b.rar"
+		}))
+		if err != nil || len(res.Parts) != 1 || string(res.Parts[0].Body) != "b.r" {
+			t.Errorf("%q: expected to skip the part (%v)", size, err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := encodeTestData(700 << 10)
+	var single bytes.Buffer
+	Encode(&single, "bench.bin", data)
+	var multi bytes.Buffer
+	m := &MultipartEncoder{PartSize: 100 << 10}
+	m.Encode(bytes.NewReader(data), "bench.bin", int64(len(data)), func(part, total int) (io.Writer, error) {
+		return &multi, nil
+	})
+	for _, bench := range []struct {
+		name  string
+		input []byte
+	}{
+		{"singlepart", single.Bytes()},
+		{"multipart", multi.Bytes()},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeAll(bytes.NewReader(bench.input)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}